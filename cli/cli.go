@@ -15,6 +15,7 @@ func main() {
 	c.Args = os.Args[1:]
 	c.Commands = map[string]cli.CommandFactory{
 		"stream": cmd.NewStreamCommand,
+		"list":   cmd.NewListCommand,
 	}
 
 	exitStatus, err := c.Run()
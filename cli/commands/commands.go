@@ -1,4 +1,122 @@
 package commands
 
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/rs/zerolog"
+)
+
 type CommandsFactory struct {
 }
+
+func getConfig(ctx context.Context, args []string) (*config.Config, error) {
+	logger := zerolog.Ctx(ctx)
+	configPath := "./config.yaml"
+	if len(args) > 0 {
+		configPath = args[0]
+	}
+
+	logger.Info().Msgf("Parse config file: %s", configPath)
+
+	return config.ParseConfigFromFile(configPath)
+}
+
+// initVideoStorage создает хранилище из source или объединяет несколько хранилищ из sources
+func initVideoStorage(ctx context.Context, cfg *config.Config, videoSchedule *schedule.Schedule) (storage.Storage, error) {
+	if len(cfg.Sources) == 0 {
+		return initStorage(ctx, cfg.Source, videoSchedule)
+	}
+
+	sources := make([]storage.MultiSource, 0, len(cfg.Sources))
+	for _, sourceCfg := range cfg.Sources {
+		sourceStorage, err := initStorage(ctx, sourceCfg, nil)
+		if err != nil {
+			return nil, fmt.Errorf("source '%s': %w", sourceCfg.Name, err)
+		}
+
+		sources = append(sources, storage.MultiSource{
+			Name:         sourceCfg.Name,
+			Weight:       sourceCfg.Weight,
+			PickStrategy: sourceCfg.PickStrategy,
+			Storage:      sourceStorage,
+		})
+	}
+
+	return storage.NewMultiStorage(ctx, storage.MultiStorageParams{
+		Schedule: videoSchedule,
+		Sources:  sources,
+	})
+}
+
+func initStorage(ctx context.Context, cfg config.ConfigSource, videoSchedule *schedule.Schedule) (storage.Storage, error) {
+	logger := zerolog.Ctx(ctx)
+	logger.Info().Msgf("Init storage: %s", cfg.Type)
+
+	switch cfg.Type {
+	case config.SourceTypeS3:
+		return storage.NewS3Storage(ctx, storage.S3StorageParams{
+			Bucket:            cfg.S3Bucket,
+			Endpoint:          cfg.S3Endpoint,
+			CredentialsID:     cfg.S3Credentials.ID,
+			CredentialsSecret: cfg.S3Credentials.Secret,
+			Region:            cfg.S3Region,
+			PickStrategy:      cfg.PickStrategy,
+			Schedule:          videoSchedule,
+			DirectoryPath:     cfg.DirectoryPath,
+			Prefixes:          cfg.Prefixes,
+			Files:             cfg.Files,
+			Extensions:        cfg.Extensions,
+			RelistInterval:    cfg.RelistIntervalDuration(),
+		})
+	case config.SourceTypeDisk:
+		return storage.NewDiskStorage(ctx, storage.DiskStorageParams{
+			PickStrategy:  cfg.PickStrategy,
+			Schedule:      videoSchedule,
+			DirectoryPath: cfg.DirectoryPath,
+			Files:         cfg.Files,
+			Extensions:    cfg.Extensions,
+			Watch:         cfg.Watch,
+			QuietPeriod:   cfg.WatchQuietPeriodDuration(),
+		})
+	case config.SourceTypePlaylist:
+		return storage.NewPlaylistStorage(ctx, storage.PlaylistStorageParams{
+			PickStrategy:        cfg.PickStrategy,
+			Schedule:            videoSchedule,
+			Playlist:            cfg.Playlist,
+			Extensions:          cfg.Extensions,
+			S3Endpoint:          cfg.S3Endpoint,
+			S3CredentialsID:     cfg.S3Credentials.ID,
+			S3CredentialsSecret: cfg.S3Credentials.Secret,
+			S3Region:            cfg.S3Region,
+		})
+	case config.SourceTypeHTTP:
+		return storage.NewHTTPStorage(ctx, storage.HTTPStorageParams{
+			PickStrategy: cfg.PickStrategy,
+			Schedule:     videoSchedule,
+			URL:          cfg.URL,
+			Files:        cfg.Files,
+			Extensions:   cfg.Extensions,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage type '%s'", cfg.Type)
+	}
+}
+
+// readTokenFile читает токен из файла, убирая переводы строк и пробелы
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	token := strings.ReplaceAll(string(data), "\n", "")
+	token = strings.TrimSpace(token)
+
+	return token, nil
+}
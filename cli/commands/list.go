@@ -0,0 +1,208 @@
+package commands
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/hashicorp/cli"
+	"github.com/rs/zerolog"
+)
+
+const (
+	listSortName     = "name"
+	listSortSize     = "size"
+	listSortDuration = "duration"
+)
+
+type ListCommand struct {
+}
+
+type listItem struct {
+	Key      string   `json:"key"`
+	Size     int64    `json:"size"`
+	Duration float64  `json:"duration"`
+	Title    string   `json:"title,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Valid    bool     `json:"valid"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+func (f *CommandsFactory) NewListCommand() (cli.Command, error) {
+	return &ListCommand{}, nil
+}
+
+func (c *ListCommand) Help() string {
+	return strings.TrimSpace(`
+Usage: StatiStream list [options] [config.yaml]
+
+  Initializes the configured video storage and prints the library:
+  size, duration, metadata and whether the file can be streamed.
+
+Options:
+
+  -json              Print the list as JSON
+  -filter=<text>     Show only files whose key, title, category or tag contains the text
+  -sort=<field>      Sort by name, size or duration (default: name)
+  -valid             Show only files that can be streamed
+`)
+}
+
+func (c *ListCommand) Synopsis() string {
+	return "Print the video library of the configured storage"
+}
+
+func (c *ListCommand) Run(args []string) int {
+	var jsonOutput, onlyValid bool
+	var filter, sortField string
+
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, c.Help()) }
+	flags.BoolVar(&jsonOutput, "json", false, "")
+	flags.BoolVar(&onlyValid, "valid", false, "")
+	flags.StringVar(&filter, "filter", "", "")
+	flags.StringVar(&sortField, "sort", listSortName, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if !slices.Contains([]string{listSortName, listSortSize, listSortDuration}, sortField) {
+		fmt.Fprintf(os.Stderr, "unknown sort field '%s'\n", sortField)
+		return 1
+	}
+
+	// Логи пишем в stderr, чтобы не мешать выводу в stdout
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
+	ctx := logger.WithContext(context.Background())
+
+	cfg, err := getConfig(ctx, flags.Args())
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to parse config")
+		return 1
+	}
+
+	videoStorage, err := initVideoStorage(ctx, cfg, nil)
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to init storage")
+		return 1
+	}
+
+	items := filterListItems(videoStorage.GetFilesInfo(), filter, onlyValid)
+	sortListItems(items, sortField)
+
+	if jsonOutput {
+		err = writeListJSON(os.Stdout, items)
+	} else {
+		err = writeListTable(os.Stdout, items)
+	}
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to print files list")
+		return 1
+	}
+
+	return 0
+}
+
+func filterListItems(files []storage.FileInfo, filter string, onlyValid bool) []listItem {
+	items := make([]listItem, 0, len(files))
+	for _, file := range files {
+		if onlyValid && file.Excluded {
+			continue
+		}
+
+		if filter != "" && !file.Matches(filter) {
+			continue
+		}
+
+		items = append(items, listItem{
+			Key:      file.Key,
+			Size:     file.Size,
+			Duration: file.Meta.Duration.Seconds(),
+			Title:    file.Meta.Title,
+			Category: file.Meta.Category,
+			Tags:     file.Meta.Tags,
+			Valid:    !file.Excluded,
+			Reason:   file.Reason,
+		})
+	}
+
+	return items
+}
+
+func sortListItems(items []listItem, field string) {
+	slices.SortStableFunc(items, func(a, b listItem) int {
+		switch field {
+		case listSortSize:
+			return cmp.Compare(b.Size, a.Size)
+		case listSortDuration:
+			return cmp.Compare(b.Duration, a.Duration)
+		default:
+			return strings.Compare(a.Key, b.Key)
+		}
+	})
+}
+
+func writeListJSON(w io.Writer, items []listItem) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(items)
+}
+
+func writeListTable(w io.Writer, items []listItem) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSIZE\tDURATION\tTITLE\tCATEGORY\tTAGS\tSTATE")
+	for _, item := range items {
+		state := "valid"
+		if !item.Valid {
+			state = "excluded: " + item.Reason
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			item.Key,
+			formatSize(item.Size),
+			formatDuration(item.Duration),
+			item.Title,
+			item.Category,
+			strings.Join(item.Tags, ","),
+			state,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+func formatDuration(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+
+	return (time.Duration(seconds) * time.Second).String()
+}
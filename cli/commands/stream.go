@@ -2,17 +2,25 @@ package commands
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/Perkovec/StatiStream/internal/api"
+	"github.com/Perkovec/StatiStream/internal/audit"
 	"github.com/Perkovec/StatiStream/internal/bot"
 	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/keys"
+	"github.com/Perkovec/StatiStream/internal/logging"
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+	"github.com/Perkovec/StatiStream/internal/player"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/Perkovec/StatiStream/internal/service"
 	"github.com/Perkovec/StatiStream/internal/storage"
 	"github.com/Perkovec/StatiStream/internal/stream"
 	telegramBot "github.com/go-telegram/bot"
@@ -20,111 +28,429 @@ import (
 	"github.com/rs/zerolog"
 )
 
+// botErrorWindow сколько времени после ошибки получения обновлений бот считается неготовым
+const botErrorWindow = time.Minute
+
 type StreamCommand struct {
 }
 
+// keyFileFlags собирает повторяющиеся флаги --key-file platform=path
+type keyFileFlags keys.Sources
+
+func (f keyFileFlags) String() string {
+	pairs := make([]string, 0, len(f))
+	for platform, source := range f {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", platform, source.File))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (f keyFileFlags) Set(value string) error {
+	platform, path, ok := strings.Cut(value, "=")
+	if !ok || platform == "" || path == "" {
+		return fmt.Errorf("invalid key file '%s', expected platform=path", value)
+	}
+
+	f[config.Platform(platform)] = keys.Source{File: path}
+
+	return nil
+}
+
 func (f *CommandsFactory) NewStreamCommand() (cli.Command, error) {
 	return &StreamCommand{}, nil
 }
 
 func (c *StreamCommand) Help() string {
-	return ""
+	return strings.TrimSpace(`
+Usage: StatiStream stream [options] [config.yaml]
+
+  Starts the service. By default the stream is controlled through
+  the Telegram bot and stream keys are set only via the bot, unless
+  stream_key_file or stream_keys are specified in the config.
+
+Options:
+
+  -autostart                   Start streaming right away without waiting for the bot
+  -key-file=<platform>=<path>  Read the stream key for a platform from a file,
+                               can be repeated, overrides the config. Without it
+                               and without a config source the key is read from
+                               the STATISTREAM_<PLATFORM>_KEY environment variable
+                               when -autostart is set
+`)
 }
 
 func (c *StreamCommand) Synopsis() string {
-	return ""
+	return "Start streaming videos to the configured platforms"
 }
 
 func (c *StreamCommand) Run(args []string) int {
+	var autostart bool
+	keyFiles := keyFileFlags{}
+
+	flags := flag.NewFlagSet("stream", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, c.Help()) }
+	flags.BoolVar(&autostart, "autostart", false, "")
+	flags.Var(keyFiles, "key-file", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	ctx, logger, err := initLogger(ctx)
+	bootstrapLogger := logging.Bootstrap()
+	ctx = bootstrapLogger.WithContext(ctx)
+
+	cfg, err := getConfig(ctx, flags.Args())
 	if err != nil {
-		log.Fatal(err)
+		bootstrapLogger.Error().Err(err).Msg("Unable to load config")
+		return 1
 	}
 
-	cfg, err := c.getConfig(ctx, args)
+	logger, logCloser, err := logging.New(cfg.Log)
 	if err != nil {
-		log.Fatal(err)
+		bootstrapLogger.Error().Err(err).Msg("Unable to create logger")
+		return 1
 	}
+	defer logCloser.Close()
+	ctx = logger.WithContext(ctx)
 
-	videoStorage, err := c.initStorage(ctx, cfg.Source)
+	videoSchedule, err := schedule.New(cfg.Schedule)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error().Err(err).Msg("Unable to create schedule")
+		return 1
 	}
 
-	streams := c.initStreams(ctx, cfg)
+	if !cfg.Bot.IsEnabled() && !cfg.API.IsEnabled() && !autostart && !videoSchedule.HasOnAir() {
+		logger.Error().Msg("Neither telegram bot nor http api is configured, use -autostart or schedule.on_air to stream without them")
+		return 1
+	}
 
-	bot, err := c.initTelegramBot(
-		ctx,
-		cfg.Bot,
-		videoStorage,
-		streams,
-	)
+	videoStorage, err := initVideoStorage(logging.WithComponent(ctx, "storage"), cfg, videoSchedule)
 	if err != nil {
-		log.Fatal(err)
+		logger.Error().Err(err).Msg("Unable to initialize video storage")
+		return 1
+	}
+	if cfg.Cache.IsEnabled() {
+		videoStorage, err = storage.NewCachedStorage(logging.WithComponent(ctx, "cache"), videoStorage, storage.CacheParams{
+			Directory: cfg.Cache.Directory,
+			MaxSize:   cfg.Cache.MaxSizeBytes(),
+		})
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to initialize video cache")
+			return 1
+		}
+	}
+	videoStorage = monitoring.InstrumentStorage(videoStorage)
+
+	logs := stream.NewLogs(stream.DefaultLogsHistory)
+	streams := c.initStreams(ctx, cfg, logs)
+
+	keysCtx := logging.WithComponent(ctx, "keys")
+	vault, err := c.initKeyVault(keysCtx, cfg.KeyVault, streams)
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to initialize key vault")
+		return 1
+	}
+
+	// Незагруженные ключи не мешают запуску, без них не стартует только -autostart
+	keys.Apply(keysCtx, streams, c.keySources(cfg, keyFiles, autostart, streams))
+
+	videoPlayer := player.New(player.PlayerParams{
+		VideoStorage: videoStorage,
+		Streams:      streams,
+		Schedule:     videoSchedule,
+	})
+	go videoPlayer.Run(logging.WithComponent(ctx, "player"))
+	defer streams.Stop()
+
+	streamService := service.New(service.ServiceParams{
+		VideoStorage: videoStorage,
+		Streams:      streams,
+		Player:       videoPlayer,
+	})
+
+	if autostart {
+		logger.Info().Msg("Autostart stream")
+		err = streamService.Start(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to autostart stream")
+			return 1
+		}
+	}
+
+	botStatus := &monitoring.BotStatus{}
+
+	if cfg.Monitoring.IsEnabled() {
+		if cfg.Monitoring.Metrics {
+			monitoring.RegisterQueueLength(func() int { return len(videoStorage.GetQueue()) })
+		}
+
+		var health *monitoring.Health
+		if cfg.Monitoring.Health {
+			health = c.initHealth(cfg, videoStorage, streams, videoPlayer, botStatus)
+		}
+
+		monitoringServer := monitoring.NewServer(monitoring.ServerParams{
+			Listen:  cfg.Monitoring.Listen,
+			Metrics: cfg.Monitoring.Metrics,
+			Health:  health,
+		})
+
+		go func() {
+			if err := monitoringServer.Run(logging.WithComponent(ctx, "monitoring")); err != nil {
+				logger.Error().Err(err).Msg("Monitoring server stopped")
+			}
+		}()
+	}
+
+	// Журнал аудита пишут бот, HTTP API и расписание
+	var auditLog *audit.Log
+	if cfg.Bot.IsEnabled() || cfg.API.IsEnabled() || videoSchedule.HasOnAir() {
+		auditLog, err = audit.Open(cfg.AuditLog)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to open audit log")
+			return 1
+		}
+		defer auditLog.Close()
+	}
+
+	if cfg.API.IsEnabled() {
+		apiServer, err := c.initAPIServer(cfg.API, streamService, logs, auditLog)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to initialize HTTP API")
+			return 1
+		}
+
+		go func() {
+			if err := apiServer.Run(logging.WithComponent(ctx, "api")); err != nil {
+				logger.Error().Err(err).Msg("HTTP API stopped")
+			}
+		}()
+	}
+
+	var telegram *telegramBot.Bot
+	botCtx := logging.WithComponent(ctx, "bot")
+	if cfg.Bot.IsEnabled() {
+		telegram, err = c.initTelegramBot(
+			botCtx,
+			cfg,
+			streamService,
+			streams,
+			vault,
+			botStatus,
+			auditLog,
+		)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to initialize telegram bot")
+			return 1
+		}
+	}
+
+	var notifier *bot.Notifier
+	if telegram != nil {
+		notifier = bot.NewNotifier(telegram, cfg.Bot.UserRoles())
+	}
+
+	if videoSchedule.HasOnAir() {
+		var scheduleNotifier service.ScheduleNotifier
+		if notifier != nil {
+			scheduleNotifier = notifier
+		}
+
+		go streamService.RunSchedule(logging.WithComponent(ctx, "schedule"), videoSchedule, scheduleNotifier, auditLog)
+	}
+
+	var libraryNotifier service.LibraryNotifier
+	if notifier != nil {
+		libraryNotifier = notifier
+	}
+	if streamService.WatchLibrary(logging.WithComponent(ctx, "watcher"), libraryNotifier) {
+		logger.Info().Msg("Watching video sources for changes")
+	}
+
+	if telegram == nil {
+		<-ctx.Done()
+		logger.Info().Msg("Stopping stream")
+		return 0
 	}
 
 	logger.Info().Msg("Starting bot")
-	bot.Start(ctx)
+	botStatus.SetPolling(true)
+	defer botStatus.SetPolling(false)
+
+	if cfg.Bot.Webhook.IsEnabled() {
+		webhook := cfg.Bot.Webhook
+		err := bot.NewWebhookServer(telegram, bot.WebhookParams{
+			Listen:      webhook.Listen,
+			URL:         webhook.URL,
+			SecretToken: webhook.SecretToken,
+			CertFile:    webhook.CertFile,
+			KeyFile:     webhook.KeyFile,
+			SelfSigned:  webhook.SelfSigned,
+		}).Run(botCtx)
+		if err != nil {
+			logger.Error().Err(err).Msg("Telegram bot webhook stopped")
+			return 1
+		}
+
+		return 0
+	}
+
+	telegram.Start(botCtx)
 
 	return 0
 }
 
-func (c *StreamCommand) getConfig(ctx context.Context, args []string) (*config.Config, error) {
+// keySources собирает источники ключей: флаги приоритетнее конфигурации, а при автозапуске
+// для платформ без ключа из хранилища используются переменные окружения
+func (c *StreamCommand) keySources(cfg *config.Config, keyFiles keyFileFlags, autostart bool, streams stream.Streams) keys.Sources {
+	sources := keys.FromConfig(cfg)
+	for platform, source := range keyFiles {
+		sources[platform] = source
+	}
+
+	if autostart {
+		for _, platform := range cfg.Platform {
+			platformStream, ok := streams[platform]
+			if !ok || platformStream.HasToken() {
+				continue
+			}
+
+			if _, ok := sources[platform]; !ok {
+				sources[platform] = keys.Source{Env: keys.EnvName(platform)}
+			}
+		}
+	}
+
+	return sources
+}
+
+// initKeyVault открывает хранилище ключей, если оно настроено, и разблокирует его паролем из окружения
+func (c *StreamCommand) initKeyVault(ctx context.Context, cfg config.ConfigKeyVault, streams stream.Streams) (*keys.Vault, error) {
 	logger := zerolog.Ctx(ctx)
-	configPath := "./config.yaml"
-	if len(args) > 0 {
-		configPath = args[0]
+
+	if !cfg.IsEnabled() {
+		return nil, nil
+	}
+
+	vault := keys.NewVault(cfg.Path)
+
+	passphrase := ""
+	if cfg.PassphraseEnv != "" {
+		passphrase = os.Getenv(cfg.PassphraseEnv)
+	}
+
+	if passphrase == "" {
+		logger.Info().Msgf("Key vault is locked, unlock it with %s bot command", bot.UnlockVaultCommand)
+		return vault, nil
+	}
+
+	err := vault.Unlock(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("StreamCommand.initKeyVault: %w", err)
+	}
+
+	applied, err := keys.ApplyVault(streams, vault)
+	if err != nil {
+		return nil, fmt.Errorf("StreamCommand.initKeyVault: %w", err)
 	}
 
-	logger.Info().Msgf("Parse config file: %s", configPath)
+	logger.Info().Msgf("Key vault unlocked, stream keys loaded for platforms: %v", applied)
 
-	return config.ParseConfigFromFile(configPath)
+	return vault, nil
 }
 
-func (c *StreamCommand) initTelegramBot(ctx context.Context, cfg config.ConfigBot, storage storage.Storage, streams stream.Streams) (*telegramBot.Bot, error) {
-	botToken, err := os.ReadFile(cfg.Token)
+func (c *StreamCommand) initTelegramBot(ctx context.Context, cfg *config.Config, streamService *service.Service, streams stream.Streams, vault *keys.Vault, status *monitoring.BotStatus, auditLog *audit.Log) (*telegramBot.Bot, error) {
+	token, err := readTokenFile(cfg.Bot.Token)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	token := strings.ReplaceAll(string(botToken), "\n", "")
-	token = strings.TrimSpace(token)
+	var upload *bot.UploadParams
+	if cfg.Bot.Upload.Enabled {
+		upload = &bot.UploadParams{
+			MaxSize:    cfg.Bot.Upload.MaxSizeBytes(),
+			Directory:  cfg.Bot.Upload.Directory,
+			Transcode:  cfg.Bot.Upload.Transcode,
+			FfmpegPath: cfg.FfmpegPath,
+		}
+	}
 
 	return bot.NewBot(ctx, bot.BotParams{
-		AcceptedUsers: cfg.AcceptedUsers,
-		Token:         token,
-		VideoStorage:  storage,
-		Streams:       streams,
+		Users:     cfg.Bot.UserRoles(),
+		Token:     token,
+		Service:   streamService,
+		Streams:   streams,
+		Vault:     vault,
+		Status:    status,
+		APIServer: cfg.Bot.APIServer,
+		Upload:    upload,
+		Audit:     auditLog,
 	})
 }
 
-func (c *StreamCommand) initStorage(ctx context.Context, cfg config.ConfigSource) (storage.Storage, error) {
-	logger := zerolog.Ctx(ctx)
-	logger.Info().Msgf("Init storage: %s", cfg.Type)
-
-	switch cfg.Type {
-	case config.SourceTypeS3:
-		return storage.NewS3Storage(ctx, storage.S3StorageParams{
-			Bucket:            cfg.S3Bucket,
-			Endpoint:          cfg.S3Endpoint,
-			CredentialsID:     cfg.S3Credentials.ID,
-			CredentialsSecret: cfg.S3Credentials.Secret,
-			Region:            cfg.S3Region,
-			PickStrategy:      cfg.PickStrategy,
-			DirectoryPath:     cfg.DirectoryPath,
-			Files:             cfg.Files,
+// initHealth собирает проверки для /healthz и /readyz
+func (c *StreamCommand) initHealth(cfg *config.Config, videoStorage storage.Storage, streams stream.Streams, videoPlayer *player.Player, botStatus *monitoring.BotStatus) *monitoring.Health {
+	health := monitoring.NewHealth()
+
+	health.AddLiveness("player", videoPlayer.CheckAlive)
+
+	health.AddReadiness("storage", videoStorage.Ping)
+
+	if cfg.Bot.IsEnabled() {
+		health.AddReadiness("bot", botStatus.Check(botErrorWindow))
+	}
+
+	staleTimeout := cfg.Monitoring.StreamStaleTimeout()
+	for platform, platformStream := range streams {
+		health.AddReadiness("stream_"+string(platform), func(_ context.Context) error {
+			if !platformStream.IsStarted() {
+				return errors.New("stream is not started")
+			}
+
+			if lastWrite := platformStream.LastWrite(); time.Since(lastWrite) > staleTimeout {
+				return fmt.Errorf("no video written to ffmpeg for %s", staleTimeout)
+			}
+
+			return nil
 		})
-	case config.SourceTypeDisk:
-		return storage.NewDiskStorage(storage.DiskStorageParams{}), nil
-	default:
-		return nil, fmt.Errorf("unknown storage type '%s'", cfg.Type)
 	}
+
+	return health
 }
 
-func (c *StreamCommand) initStreams(ctx context.Context, cfg *config.Config) stream.Streams {
+func (c *StreamCommand) initAPIServer(cfg config.ConfigAPI, streamService *service.Service, logs *stream.Logs, auditLog *audit.Log) (*api.Server, error) {
+	token, err := readTokenFile(cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("StreamCommand.initAPIServer: %w", err)
+	}
+
+	if token == "" {
+		return nil, errors.New("StreamCommand.initAPIServer: empty http api token")
+	}
+
+	password := ""
+	if cfg.DashboardPassword != "" {
+		password, err = readTokenFile(cfg.DashboardPassword)
+		if err != nil {
+			return nil, fmt.Errorf("StreamCommand.initAPIServer: %w", err)
+		}
+	}
+
+	return api.NewServer(api.ServerParams{
+		Service:   streamService,
+		Logs:      logs,
+		Audit:     auditLog,
+		Listen:    cfg.Listen,
+		Token:     token,
+		Dashboard: cfg.Dashboard,
+		Password:  password,
+	}), nil
+}
+
+func (c *StreamCommand) initStreams(ctx context.Context, cfg *config.Config, logs *stream.Logs) stream.Streams {
 	logger := zerolog.Ctx(ctx)
 	streams := make(stream.Streams, len(cfg.Platform))
 	for _, platform := range cfg.Platform {
@@ -133,28 +459,11 @@ func (c *StreamCommand) initStreams(ctx context.Context, cfg *config.Config) str
 		case config.PlatformTwitch:
 			streams[platform] = stream.NewTwitchStream(stream.TwitchStreamParams{
 				FfmpegPath: cfg.FfmpegPath,
+				Logs:       logs,
+				Logger:     logger.With().Str("component", "stream").Logger(),
 			})
 		}
 	}
 
 	return streams
 }
-
-func initLogger(ctx context.Context) (context.Context, zerolog.Logger, error) {
-	err := os.MkdirAll(filepath.Join(".", "logs"), os.ModePerm)
-	if err != nil {
-		return ctx, zerolog.Logger{}, err
-	}
-
-	logFile, _ := os.OpenFile(
-		fmt.Sprintf("./logs/statistream_%s", time.Now().Format("2006-01-02")),
-		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-		0664,
-	)
-
-	multiLogOutput := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stdout}, logFile)
-	logger := zerolog.New(multiLogOutput).With().Timestamp().Logger()
-	ctx = logger.WithContext(ctx)
-
-	return ctx, logger, nil
-}
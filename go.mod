@@ -2,11 +2,26 @@ module github.com/Perkovec/StatiStream
 
 go 1.23.4
 
-require github.com/hashicorp/cli v1.1.6
+require (
+	github.com/hashicorp/cli v1.1.6
+	github.com/matoous/go-nanoid/v2 v2.1.0
+	github.com/rs/zerolog v1.33.0
+)
+
+require gopkg.in/natefinch/lumberjack.v2 v2.2.1
+
+require github.com/fsnotify/fsnotify v1.7.0
 
 require (
-	github.com/matoous/go-nanoid/v2 v2.1.0 // indirect
-	github.com/rs/zerolog v1.33.0 // indirect
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.3.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/munnerz/goautoneg v0.0.0-20191010083416-a7dc8b61c822 // indirect
+	github.com/prometheus/client_golang v1.20.5
+	github.com/prometheus/client_model v0.6.1 // indirect
+	github.com/prometheus/common v0.55.0 // indirect
+	github.com/prometheus/procfs v0.15.1 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
 )
 
 require (
@@ -14,12 +29,11 @@ require (
 	github.com/Masterminds/semver/v3 v3.2.0 // indirect
 	github.com/Masterminds/sprig/v3 v3.2.3 // indirect
 	github.com/armon/go-radix v1.0.0 // indirect
-	github.com/aws/aws-sdk-go v1.55.5 // indirect
+	github.com/aws/aws-sdk-go v1.55.5
 	github.com/bgentry/speakeasy v0.1.0 // indirect
 	github.com/fatih/color v1.16.0 // indirect
-	github.com/go-telegram/bot v1.7.2 // indirect
-	github.com/goccy/go-yaml v1.12.0 // indirect
-	github.com/golang-jwt/jwt/v4 v4.5.0 // indirect
+	github.com/go-telegram/bot v1.7.2
+	github.com/goccy/go-yaml v1.12.0
 	github.com/google/uuid v1.1.2 // indirect
 	github.com/hashicorp/errwrap v1.0.0 // indirect
 	github.com/hashicorp/go-multierror v1.0.0 // indirect
@@ -30,11 +44,10 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/mitchellh/copystructure v1.0.0 // indirect
 	github.com/mitchellh/reflectwalk v1.0.0 // indirect
-	github.com/nicklaw5/helix/v2 v2.31.0 // indirect
 	github.com/posener/complete v1.2.3 // indirect
 	github.com/shopspring/decimal v1.2.0 // indirect
 	github.com/spf13/cast v1.3.1 // indirect
-	golang.org/x/crypto v0.7.0 // indirect
+	golang.org/x/crypto v0.7.0
 	golang.org/x/sys v0.25.0 // indirect
 	golang.org/x/text v0.18.0
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
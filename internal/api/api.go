@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/player"
+	"github.com/Perkovec/StatiStream/internal/service"
+	"github.com/Perkovec/StatiStream/internal/stream"
+	"github.com/rs/zerolog"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// actorContextKey способ входа, которым прошел запрос, для журнала аудита
+type actorContextKey struct{}
+
+// Server HTTP JSON API для управления трансляцией без телеграмм бота и веб-интерфейс поверх него
+type Server struct {
+	service   *service.Service
+	audit     *audit.Log
+	logs      *stream.Logs
+	listen    string
+	token     string
+	dashboard bool
+	password  string
+	sessions  *sessions
+	logins    *loginAttempts
+}
+
+type ServerParams struct {
+	Service *service.Service
+	Logs    *stream.Logs
+	// Audit необязательно, журнал действий управления
+	Audit     *audit.Log
+	Listen    string
+	Token     string
+	Dashboard bool
+	Password  string
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type okResponse struct {
+	OK bool `json:"ok"`
+}
+
+func NewServer(params ServerParams) *Server {
+	return &Server{
+		service:   params.Service,
+		audit:     params.Audit,
+		logs:      params.Logs,
+		listen:    params.Listen,
+		token:     params.Token,
+		dashboard: params.Dashboard,
+		password:  params.Password,
+		sessions:  newSessions(),
+		logins:    newLoginAttempts(),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.Handle("GET /status", s.authMiddleware(s.handleStatus))
+	mux.Handle("POST /start", s.authMiddleware(s.handleStart))
+	mux.Handle("POST /stop", s.authMiddleware(s.handleStop))
+	mux.Handle("POST /next", s.authMiddleware(s.handleNext))
+	mux.Handle("GET /queue", s.authMiddleware(s.handleQueue))
+	mux.Handle("POST /queue", s.authMiddleware(s.handleAddToQueue))
+	mux.Handle("PUT /queue", s.authMiddleware(s.handleSetQueue))
+	mux.Handle("GET /library", s.authMiddleware(s.handleLibrary))
+	mux.Handle("POST /library/reload", s.authMiddleware(s.handleReloadLibrary))
+	mux.Handle("GET /logs", s.authMiddleware(s.handleLogs))
+
+	if s.dashboard {
+		mux.HandleFunc("POST /login", s.handleLogin)
+		mux.HandleFunc("POST /logout", s.handleLogout)
+		mux.Handle("GET /", dashboardHandler())
+	}
+
+	return mux
+}
+
+// Run слушает адрес до отмены контекста, после чего корректно завершает сервер
+func (s *Server) Run(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	server := &http.Server{
+		Addr:              s.listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Msgf("Starting HTTP API on %s", s.listen)
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("Server.Run.ListenAndServe: %w", err)
+	}
+
+	return nil
+}
+
+// authMiddleware пропускает запросы с токеном API в заголовке Authorization или с cookie сессии веб-интерфейса
+func (s *Server) authMiddleware(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1 {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorContextKey{}, audit.ActorAPI)))
+				return
+			}
+		}
+
+		if cookie, err := r.Cookie(sessionCookieName); err == nil && s.dashboard && s.sessions.Valid(cookie.Value) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), actorContextKey{}, audit.ActorDashboard)))
+			return
+		}
+
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+	})
+}
+
+// auditAction записывает действие в журнал аудита от имени токена API или веб-интерфейса, которым прошел запрос
+func (s *Server) auditAction(r *http.Request, action audit.Action, target string, err error) {
+	if s.audit == nil {
+		return
+	}
+
+	entry := audit.NewEntry(action, target, err)
+	entry.Username, _ = r.Context().Value(actorContextKey{}).(string)
+
+	if err := s.audit.Record(entry); err != nil {
+		zerolog.Ctx(r.Context()).Error().Err(err).Str("action", string(action)).Msg("Unable to write audit log")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// errorStatus подбирает HTTP статус для ошибки сервиса
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAlreadyStarted):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrMissingKeys),
+		errors.Is(err, service.ErrUnknownVideo),
+		errors.Is(err, service.ErrUnknownPlatform),
+		errors.Is(err, service.ErrEmptyKey),
+		errors.Is(err, player.ErrNotStarted):
+		return http.StatusBadRequest
+	case errors.Is(err, player.ErrNoVideo):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/player"
+	"github.com/Perkovec/StatiStream/internal/service"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/Perkovec/StatiStream/internal/stream"
+)
+
+const testAPIToken = "test-api-token"
+
+// fakeStream стрим без ffmpeg, состояние которого задает тест
+type fakeStream struct {
+	started bool
+	token   string
+}
+
+func (s *fakeStream) Start() error                          { s.started = true; return nil }
+func (s *fakeStream) Stop() error                           { s.started = false; return nil }
+func (s *fakeStream) SetVideo(video io.ReadCloser, _ int64) { video.Close() }
+func (s *fakeStream) SetStreamToken(token string)           { s.token = token }
+func (s *fakeStream) HasToken() bool                        { return s.token != "" }
+func (s *fakeStream) IsStarted() bool                       { return s.started }
+func (s *fakeStream) NextVideo() <-chan struct{}            { return nil }
+func (s *fakeStream) LastWrite() time.Time                  { return time.Time{} }
+
+// newTestServer API поверх библиотеки из a.ts и b.ts на диске и стрима twitch
+func newTestServer(t *testing.T) (*Server, *fakeStream, *audit.Log) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range []string{"a.ts", "b.ts"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	videoStorage, err := storage.NewDiskStorage(context.Background(), storage.DiskStorageParams{
+		PickStrategy:  config.PickStrategySequential,
+		DirectoryPath: dir,
+	})
+	if err != nil {
+		t.Fatalf("NewDiskStorage: %v", err)
+	}
+
+	auditLog, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	t.Cleanup(func() { auditLog.Close() })
+
+	twitch := &fakeStream{}
+	streams := stream.Streams{config.PlatformTwitch: twitch}
+	videoPlayer := player.New(player.PlayerParams{VideoStorage: videoStorage, Streams: streams})
+
+	server := NewServer(ServerParams{
+		Service: service.New(service.ServiceParams{
+			VideoStorage: videoStorage,
+			Streams:      streams,
+			Player:       videoPlayer,
+		}),
+		Audit: auditLog,
+		Token: testAPIToken,
+	})
+
+	return server, twitch, auditLog
+}
+
+// do выполняет запрос к API с токеном и возвращает статус и тело ответа
+func do(t *testing.T, handler http.Handler, method string, target string, body string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code, rec.Body.String()
+}
+
+func TestAuthRejectsRequests(t *testing.T) {
+	server, _, auditLog := newTestServer(t)
+	handler := server.Handler()
+
+	session, err := server.sessions.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		prepare func(req *http.Request)
+		want    int
+	}{
+		{name: "no token", prepare: func(req *http.Request) {}, want: http.StatusUnauthorized},
+		{name: "wrong token", prepare: func(req *http.Request) { req.Header.Set("Authorization", "Bearer wrong") }, want: http.StatusUnauthorized},
+		{name: "token without bearer", prepare: func(req *http.Request) { req.Header.Set("Authorization", testAPIToken) }, want: http.StatusUnauthorized},
+		{
+			// Веб-интерфейс выключен, cookie сессии не принимается
+			name: "session cookie without dashboard",
+			prepare: func(req *http.Request) {
+				req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: session})
+			},
+			want: http.StatusUnauthorized,
+		},
+		{name: "valid token", prepare: func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+testAPIToken) }, want: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			test.prepare(req)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, test.want, rec.Body.String())
+			}
+		})
+	}
+
+	// Без авторизации действие не выполняется и не попадает в журнал
+	req := httptest.NewRequest(http.MethodPut, "/queue", strings.NewReader(`{"queue": ["a.ts"]}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("PUT /queue status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if queue := server.service.Queue(); len(queue) > 0 {
+		t.Fatalf("queue = %v after rejected request, want empty", queue)
+	}
+	if entries, err := auditLog.Last(10); err != nil || len(entries) > 0 {
+		t.Fatalf("audit entries = %+v, %v, want none", entries, err)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: service.ErrAlreadyStarted, want: http.StatusConflict},
+		{err: service.ErrMissingKeys, want: http.StatusBadRequest},
+		{err: service.ErrUnknownVideo, want: http.StatusBadRequest},
+		{err: service.ErrUnknownPlatform, want: http.StatusBadRequest},
+		{err: service.ErrEmptyKey, want: http.StatusBadRequest},
+		{err: player.ErrNotStarted, want: http.StatusBadRequest},
+		{err: player.ErrNoVideo, want: http.StatusServiceUnavailable},
+		{err: errors.New("disk failure"), want: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			// Ошибки приходят обернутыми в контекст операции
+			if got := errorStatus(errors.Join(errors.New("operation"), test.err)); got != test.want {
+				t.Fatalf("errorStatus(%v) = %d, want %d", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+func TestControlErrors(t *testing.T) {
+	server, twitch, auditLog := newTestServer(t)
+	handler := server.Handler()
+
+	// Ключ не задан
+	if status, body := do(t, handler, http.MethodPost, "/start", ""); status != http.StatusBadRequest {
+		t.Fatalf("POST /start without key = %d, want %d: %s", status, http.StatusBadRequest, body)
+	}
+
+	// Трансляция не запущена
+	if status, body := do(t, handler, http.MethodPost, "/next", ""); status != http.StatusBadRequest {
+		t.Fatalf("POST /next = %d, want %d: %s", status, http.StatusBadRequest, body)
+	}
+
+	// Трансляция уже идет
+	twitch.started = true
+	status, body := do(t, handler, http.MethodPost, "/start", "")
+	if status != http.StatusConflict {
+		t.Fatalf("POST /start when started = %d, want %d: %s", status, http.StatusConflict, body)
+	}
+
+	var response errorResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil || !strings.Contains(response.Error, string(config.PlatformTwitch)) {
+		t.Fatalf("error response = %s, %v, want error with platform", body, err)
+	}
+
+	entries, err := auditLog.Last(10)
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("audit entries = %+v, want 3", entries)
+	}
+	for _, entry := range entries {
+		if entry.Username != audit.ActorAPI || entry.Result != audit.ResultError {
+			t.Fatalf("audit entry = %+v, want failed action by %s", entry, audit.ActorAPI)
+		}
+	}
+}
+
+func TestQueueEndpoints(t *testing.T) {
+	server, _, _ := newTestServer(t)
+	handler := server.Handler()
+
+	queue := func(body string) []string {
+		t.Helper()
+
+		var response queueResponse
+		if err := json.Unmarshal([]byte(body), &response); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", body, err)
+		}
+
+		return response.Queue
+	}
+
+	status, body := do(t, handler, http.MethodGet, "/queue", "")
+	if status != http.StatusOK || len(queue(body)) != 0 {
+		t.Fatalf("GET /queue = %d %s, want empty queue", status, body)
+	}
+
+	status, body = do(t, handler, http.MethodPost, "/queue", `{"key": "b.ts"}`)
+	if status != http.StatusOK || !slices.Equal(queue(body), []string{"b.ts"}) {
+		t.Fatalf("POST /queue = %d %s, want [b.ts]", status, body)
+	}
+
+	status, body = do(t, handler, http.MethodPost, "/queue", `{"key": "missing.ts"}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("POST /queue with unknown video = %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+
+	status, body = do(t, handler, http.MethodPost, "/queue", `{"key": `)
+	if status != http.StatusBadRequest {
+		t.Fatalf("POST /queue with broken body = %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+
+	status, body = do(t, handler, http.MethodPut, "/queue", `{"queue": ["a.ts", "b.ts", "a.ts"]}`)
+	if status != http.StatusOK || !slices.Equal(queue(body), []string{"a.ts", "b.ts", "a.ts"}) {
+		t.Fatalf("PUT /queue = %d %s, want [a.ts b.ts a.ts]", status, body)
+	}
+
+	// Очередь с неизвестным видео не заменяет текущую
+	status, body = do(t, handler, http.MethodPut, "/queue", `{"queue": ["a.ts", "missing.ts"]}`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("PUT /queue with unknown video = %d %s, want %d", status, body, http.StatusBadRequest)
+	}
+
+	status, body = do(t, handler, http.MethodGet, "/queue", "")
+	if status != http.StatusOK || !slices.Equal(queue(body), []string{"a.ts", "b.ts", "a.ts"}) {
+		t.Fatalf("GET /queue = %d %s, want [a.ts b.ts a.ts]", status, body)
+	}
+
+	status, body = do(t, handler, http.MethodPut, "/queue", `{"queue": []}`)
+	if status != http.StatusOK || len(queue(body)) != 0 {
+		t.Fatalf("PUT /queue with empty queue = %d %s, want empty queue", status, body)
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+type loginRequest struct {
+	Password string `json:"password"`
+}
+
+func dashboardHandler() http.Handler {
+	webFS, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FileServerFS(webFS)
+}
+
+// handleLogin принимает пароль веб-интерфейса или токен API и выдает cookie сессии
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	address := remoteHost(r)
+	if wait := s.logins.Wait(address); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, errors.New("too many failed login attempts, try again later"))
+		return
+	}
+
+	var req loginRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	if !s.checkSecret(req.Password) {
+		s.logins.Fail(address)
+		writeError(w, http.StatusUnauthorized, errors.New("wrong password"))
+		return
+	}
+	s.logins.Succeed(address)
+
+	id, err := s.sessions.Create()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Secure:   r.TLS != nil,
+	})
+
+	writeJSON(w, http.StatusOK, okResponse{OK: true})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.sessions.Delete(cookie.Value)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	writeJSON(w, http.StatusOK, okResponse{OK: true})
+}
+
+// remoteHost адрес клиента без порта, за обратным прокси это адрес прокси
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (s *Server) checkSecret(secret string) bool {
+	if secret == "" {
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(s.token)) == 1 {
+		return true
+	}
+
+	return s.password != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.password)) == 1
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/stream"
+)
+
+type addToQueueRequest struct {
+	Key string `json:"key"`
+}
+
+type setQueueRequest struct {
+	Queue []string `json:"queue"`
+}
+
+type queueResponse struct {
+	Queue []string `json:"queue"`
+}
+
+type libraryItem struct {
+	Key      string   `json:"key"`
+	Size     int64    `json:"size"`
+	Duration float64  `json:"duration"`
+	Title    string   `json:"title,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Valid    bool     `json:"valid"`
+	Reason   string   `json:"reason,omitempty"`
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.service.Status())
+}
+
+func (s *Server) handleStart(w http.ResponseWriter, r *http.Request) {
+	err := s.service.Start(r.Context())
+	s.auditAction(r, audit.ActionStart, "", err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.service.Status())
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	err := s.service.Stop(r.Context())
+	s.auditAction(r, audit.ActionStop, "", err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.service.Status())
+}
+
+func (s *Server) handleNext(w http.ResponseWriter, r *http.Request) {
+	err := s.service.Next(r.Context())
+	s.auditAction(r, audit.ActionNext, "", err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.service.Status())
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, queueResponse{Queue: s.service.Queue()})
+}
+
+func (s *Server) handleAddToQueue(w http.ResponseWriter, r *http.Request) {
+	var req addToQueueRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	err = s.service.AddToQueue(req.Key)
+	s.auditAction(r, audit.ActionQueueAdd, req.Key, err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, queueResponse{Queue: s.service.Queue()})
+}
+
+func (s *Server) handleSetQueue(w http.ResponseWriter, r *http.Request) {
+	var req setQueueRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	err = s.service.SetQueue(req.Queue)
+	s.auditAction(r, audit.ActionQueueSet, strings.Join(req.Queue, ", "), err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, queueResponse{Queue: s.service.Queue()})
+}
+
+// handleLibrary возвращает список видеозаписей, параметр q фильтрует по имени, заголовку, категории и тегам
+func (s *Server) handleLibrary(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	files := s.service.Library()
+	items := make([]libraryItem, 0, len(files))
+	for _, file := range files {
+		if query != "" && !file.Matches(query) {
+			continue
+		}
+
+		items = append(items, libraryItem{
+			Key:      file.Key,
+			Size:     file.Size,
+			Duration: file.Meta.Duration.Seconds(),
+			Title:    file.Meta.Title,
+			Category: file.Meta.Category,
+			Tags:     file.Meta.Tags,
+			Valid:    !file.Excluded,
+			Reason:   file.Reason,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleReloadLibrary(w http.ResponseWriter, r *http.Request) {
+	err := s.service.ReloadLibrary(r.Context())
+	s.auditAction(r, audit.ActionReload, "", err)
+	if err != nil {
+		writeError(w, errorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, okResponse{OK: true})
+}
+
+// handleLogs отдает вывод ffmpeg через Server-Sent Events: сначала последние строки, затем новые по мере появления
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	if s.logs == nil {
+		writeError(w, http.StatusNotFound, errors.New("logs are not available"))
+		return
+	}
+
+	lines, unsubscribe := s.logs.Subscribe()
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, line := range s.logs.History() {
+		writeLogEvent(w, line)
+	}
+	rc.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-lines:
+			writeLogEvent(w, line)
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func writeLogEvent(w http.ResponseWriter, line stream.LogLine) {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return
+	}
+
+	fmt.Fprintf(w, "data: %s\n\n", data)
+}
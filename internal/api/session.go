@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookieName = "statistream_session"
+	sessionTTL        = 12 * time.Hour
+)
+
+// sessions хранит в памяти сессии веб-интерфейса, после перезапуска нужно войти заново
+type sessions struct {
+	mu    sync.Mutex
+	items map[string]time.Time
+}
+
+func newSessions() *sessions {
+	return &sessions{
+		items: map[string]time.Time{},
+	}
+}
+
+func (s *sessions) Create() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	id := hex.EncodeToString(b)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, expiresAt := range s.items {
+		if now.After(expiresAt) {
+			delete(s.items, key)
+		}
+	}
+
+	s.items[id] = now.Add(sessionTTL)
+
+	return id, nil
+}
+
+func (s *sessions) Valid(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiresAt, ok := s.items[id]
+	if !ok {
+		return false
+	}
+
+	if time.Now().After(expiresAt) {
+		delete(s.items, id)
+		return false
+	}
+
+	return true
+}
+
+func (s *sessions) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.items, id)
+}
+
+const (
+	// loginFreeAttempts неудачные попытки входа с одного адреса без задержки
+	loginFreeAttempts = 3
+	loginMaxDelay     = 5 * time.Minute
+	// loginAddressesLimit сколько адресов запоминается, неудачные попытки остальных адресов считаются вместе
+	loginAddressesLimit = 10000
+)
+
+type loginFailures struct {
+	count        int
+	blockedUntil time.Time
+	lastFailure  time.Time
+}
+
+// loginAttempts замедляет подбор пароля: после нескольких неудачных попыток входа с адреса каждая следующая
+// удваивает время, через которое с него можно попробовать снова
+type loginAttempts struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	items map[string]*loginFailures
+}
+
+func newLoginAttempts() *loginAttempts {
+	return &loginAttempts{
+		now:   time.Now,
+		items: map[string]*loginFailures{},
+	}
+}
+
+// Wait возвращает, сколько адресу осталось ждать до следующей попытки входа
+func (l *loginAttempts) Wait(address string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures, ok := l.items[l.bucket(address)]
+	if !ok {
+		return 0
+	}
+
+	return max(failures.blockedUntil.Sub(l.now()), 0)
+}
+
+func (l *loginAttempts) Fail(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	// Адреса, с которых давно не ошибались, забываются
+	for key, failures := range l.items {
+		if now.Sub(failures.lastFailure) > 2*loginMaxDelay {
+			delete(l.items, key)
+		}
+	}
+
+	key := l.bucket(address)
+	failures, ok := l.items[key]
+	if !ok {
+		failures = &loginFailures{}
+		l.items[key] = failures
+	}
+
+	failures.count++
+	failures.lastFailure = now
+	if failures.count > loginFreeAttempts {
+		delay := loginMaxDelay
+		if shift := failures.count - loginFreeAttempts - 1; shift < 16 {
+			delay = min(time.Second<<shift, loginMaxDelay)
+		}
+		failures.blockedUntil = now.Add(delay)
+	}
+}
+
+func (l *loginAttempts) Succeed(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.items, address)
+}
+
+// bucket адрес, под которым учитываются попытки, при переполнении новые адреса делят одну запись
+func (l *loginAttempts) bucket(address string) string {
+	if _, ok := l.items[address]; ok || len(l.items) < loginAddressesLimit {
+		return address
+	}
+
+	return ""
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginAttemptsBackoff(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	logins := newLoginAttempts()
+	logins.now = func() time.Time { return now }
+
+	for range loginFreeAttempts {
+		logins.Fail("10.0.0.1")
+	}
+	if wait := logins.Wait("10.0.0.1"); wait != 0 {
+		t.Fatalf("Wait() after free attempts = %s, want 0", wait)
+	}
+
+	logins.Fail("10.0.0.1")
+	logins.Fail("10.0.0.1")
+	if wait := logins.Wait("10.0.0.1"); wait != 2*time.Second {
+		t.Fatalf("Wait() = %s, want 2s", wait)
+	}
+	// Другой адрес не ждет
+	if wait := logins.Wait("10.0.0.2"); wait != 0 {
+		t.Fatalf("Wait() for other address = %s, want 0", wait)
+	}
+
+	for range 30 {
+		logins.Fail("10.0.0.1")
+	}
+	if wait := logins.Wait("10.0.0.1"); wait != loginMaxDelay {
+		t.Fatalf("Wait() = %s, want %s", wait, loginMaxDelay)
+	}
+
+	logins.Succeed("10.0.0.1")
+	if wait := logins.Wait("10.0.0.1"); wait != 0 {
+		t.Fatalf("Wait() after successful login = %s, want 0", wait)
+	}
+}
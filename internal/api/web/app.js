@@ -0,0 +1,258 @@
+'use strict';
+
+const $ = (id) => document.getElementById(id);
+
+let logsSource = null;
+let libraryTimer = null;
+let statusTimer = null;
+let dragged = null;
+
+async function request(method, path, body) {
+    const response = await fetch(path, {
+        method,
+        headers: body ? { 'Content-Type': 'application/json' } : {},
+        body: body ? JSON.stringify(body) : undefined,
+        credentials: 'same-origin',
+    });
+
+    const data = await response.json().catch(() => ({}));
+    if (response.status === 401) {
+        showLogin();
+        throw new Error('Требуется вход');
+    }
+    if (!response.ok) {
+        throw new Error(data.error || response.statusText);
+    }
+
+    return data;
+}
+
+function showLogin() {
+    $('dashboard').classList.add('hidden');
+    $('login').classList.remove('hidden');
+    clearInterval(statusTimer);
+    if (logsSource) {
+        logsSource.close();
+        logsSource = null;
+    }
+}
+
+function showDashboard() {
+    $('login').classList.add('hidden');
+    $('dashboard').classList.remove('hidden');
+    refreshStatus();
+    refreshLibrary();
+    connectLogs();
+    clearInterval(statusTimer);
+    statusTimer = setInterval(refreshStatus, 2000);
+}
+
+function formatDuration(seconds) {
+    seconds = Math.max(0, Math.floor(seconds));
+    const h = Math.floor(seconds / 3600);
+    const m = Math.floor((seconds % 3600) / 60);
+    const s = seconds % 60;
+    return [h, m, s].map((v) => String(v).padStart(2, '0')).join(':');
+}
+
+function renderStatus(status) {
+    const current = status.current_video;
+    if (current) {
+        let text = current.title || current.filename;
+        if (current.category) {
+            text += ` · ${current.category}`;
+        }
+        if (current.duration > 0) {
+            text += ` · ${formatDuration(current.duration * current.progress)} / ${formatDuration(current.duration)}`;
+        }
+        $('current').textContent = text;
+        $('current-progress').value = current.progress;
+    } else {
+        $('current').textContent = 'Трансляция не запущена';
+        $('current-progress').value = 0;
+    }
+
+    const platforms = $('platforms');
+    platforms.replaceChildren(...status.platforms.map((platform) => {
+        const item = document.createElement('li');
+        item.classList.toggle('started', platform.started);
+        item.textContent = `${platform.platform}: ${platform.started ? 'в эфире' : 'остановлен'}${platform.has_key ? '' : ', нет ключа'}`;
+        return item;
+    }));
+
+    if (!dragged) {
+        renderQueue(status.queue);
+    }
+}
+
+function renderQueue(queue) {
+    $('queue').replaceChildren(...queue.map((key, index) => {
+        const item = document.createElement('li');
+        item.draggable = true;
+        item.dataset.key = key;
+        item.textContent = key;
+
+        const remove = document.createElement('button');
+        remove.className = 'secondary';
+        remove.textContent = '✕';
+        remove.addEventListener('click', () => {
+            const next = queue.slice();
+            next.splice(index, 1);
+            saveQueue(next);
+        });
+        item.append(remove);
+
+        return item;
+    }));
+}
+
+function currentQueue() {
+    return Array.from($('queue').children).map((item) => item.dataset.key);
+}
+
+async function saveQueue(queue) {
+    try {
+        const data = await request('PUT', '/queue', { queue });
+        renderQueue(data.queue);
+        $('action-error').textContent = '';
+    } catch (err) {
+        $('action-error').textContent = err.message;
+    }
+}
+
+async function refreshStatus() {
+    try {
+        renderStatus(await request('GET', '/status'));
+    } catch (err) {
+        $('action-error').textContent = err.message;
+    }
+}
+
+async function refreshLibrary() {
+    const query = $('library-search').value.trim();
+    try {
+        const files = await request('GET', '/library' + (query ? `?q=${encodeURIComponent(query)}` : ''));
+        $('library').replaceChildren(...files.map((file) => {
+            const item = document.createElement('li');
+            item.classList.toggle('excluded', !file.valid);
+
+            const info = document.createElement('span');
+            info.textContent = file.title ? `${file.title} (${file.key})` : file.key;
+            const details = document.createElement('small');
+            details.textContent = ' ' + [
+                file.duration > 0 ? formatDuration(file.duration) : '',
+                file.category || '',
+                (file.tags || []).join(', '),
+                file.valid ? '' : file.reason,
+            ].filter(Boolean).join(' · ');
+            info.append(details);
+            item.append(info);
+
+            if (file.valid) {
+                const add = document.createElement('button');
+                add.textContent = 'В очередь';
+                add.addEventListener('click', async () => {
+                    try {
+                        const data = await request('POST', '/queue', { key: file.key });
+                        renderQueue(data.queue);
+                    } catch (err) {
+                        $('action-error').textContent = err.message;
+                    }
+                });
+                item.append(add);
+            }
+
+            return item;
+        }));
+    } catch (err) {
+        $('action-error').textContent = err.message;
+    }
+}
+
+function connectLogs() {
+    if (logsSource) {
+        logsSource.close();
+    }
+
+    const logs = $('logs');
+    logs.textContent = '';
+    logsSource = new EventSource('/logs');
+    logsSource.onmessage = (event) => {
+        const line = JSON.parse(event.data);
+        const atBottom = logs.scrollTop + logs.clientHeight >= logs.scrollHeight - 4;
+        logs.textContent += `${new Date(line.time).toLocaleTimeString()} [${line.platform}] ${line.level}: ${line.text}\n`;
+        if (logs.textContent.length > 200000) {
+            logs.textContent = logs.textContent.slice(-100000);
+        }
+        if (atBottom) {
+            logs.scrollTop = logs.scrollHeight;
+        }
+    };
+}
+
+$('login-form').addEventListener('submit', async (event) => {
+    event.preventDefault();
+    try {
+        await request('POST', '/login', { password: $('login-password').value });
+        $('login-password').value = '';
+        $('login-error').textContent = '';
+        showDashboard();
+    } catch (err) {
+        $('login-error').textContent = err.message;
+    }
+});
+
+$('logout').addEventListener('click', async () => {
+    await request('POST', '/logout').catch(() => {});
+    showLogin();
+});
+
+document.querySelectorAll('[data-action]').forEach((button) => {
+    button.addEventListener('click', async () => {
+        if (!confirm(`Вы точно уверены? ${button.textContent}`)) {
+            return;
+        }
+        try {
+            await request('POST', '/' + button.dataset.action);
+            $('action-error').textContent = '';
+            refreshStatus();
+            if (button.dataset.action === 'library/reload') {
+                refreshLibrary();
+            }
+        } catch (err) {
+            $('action-error').textContent = err.message;
+        }
+    });
+});
+
+$('library-search').addEventListener('input', () => {
+    clearTimeout(libraryTimer);
+    libraryTimer = setTimeout(refreshLibrary, 250);
+});
+
+$('queue').addEventListener('dragstart', (event) => {
+    dragged = event.target.closest('li');
+    dragged.classList.add('dragging');
+});
+
+$('queue').addEventListener('dragover', (event) => {
+    event.preventDefault();
+    const target = event.target.closest('li');
+    if (!dragged || !target || target === dragged) {
+        return;
+    }
+    const rect = target.getBoundingClientRect();
+    const after = event.clientY > rect.top + rect.height / 2;
+    target.parentNode.insertBefore(dragged, after ? target.nextSibling : target);
+});
+
+$('queue').addEventListener('dragend', () => {
+    if (!dragged) {
+        return;
+    }
+    dragged.classList.remove('dragging');
+    dragged = null;
+    saveQueue(currentQueue());
+});
+
+request('GET', '/status').then(showDashboard).catch(showLogin);
@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// maxEntrySize максимальный размер строки журнала при чтении
+	maxEntrySize = 1024 * 1024
+	// readBlockSize размер блока, которым журнал читается с конца
+	readBlockSize = 64 * 1024
+)
+
+type Action string
+
+const (
+	ActionStart       Action = "start"
+	ActionStop        Action = "stop"
+	ActionNext        Action = "next"
+	ActionQueueAdd    Action = "queue_add"
+	ActionQueueSet    Action = "queue_set"
+	ActionReload      Action = "reload"
+	ActionSetKey      Action = "set_key"
+	ActionSaveKey     Action = "save_key"
+	ActionForgetKeys  Action = "forget_keys"
+	ActionUnlockVault Action = "unlock_vault"
+	ActionUpload      Action = "upload"
+	ActionDelete      Action = "library_delete"
+	ActionRename      Action = "library_rename"
+	ActionEditMeta    Action = "library_edit"
+)
+
+// Участники без пользователя телеграмма, записываются в поле username с user_id 0
+const (
+	ActorAPI       = "api"
+	ActorDashboard = "dashboard"
+	ActorSchedule  = "schedule"
+)
+
+type Result string
+
+const (
+	ResultOK        Result = "ok"
+	ResultError     Result = "error"
+	ResultCancelled Result = "cancelled"
+	ResultDenied    Result = "denied"
+)
+
+// Entry запись журнала, ключи трансляций и пароли в нее не попадают
+type Entry struct {
+	Time     time.Time `json:"time"`
+	UserID   int64     `json:"user_id"`
+	Username string    `json:"username,omitempty"`
+	Action   Action    `json:"action"`
+	// Target платформа или видео, к которым относится действие
+	Target string `json:"target,omitempty"`
+	Result Result `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewEntry запись о действии, err nil означает успешное действие
+func NewEntry(action Action, target string, err error) Entry {
+	entry := Entry{
+		Action: action,
+		Target: target,
+		Result: ResultOK,
+	}
+	if err != nil {
+		entry.Result = ResultError
+		entry.Error = err.Error()
+	}
+
+	return entry
+}
+
+// Log журнал действий управления в JSONL файле, записи только добавляются в конец файла
+type Log struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+func Open(path string) (*Log, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("audit.Open.MkdirAll: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit.Open.OpenFile: %w", err)
+	}
+
+	return &Log{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Record дописывает запись в журнал и сразу сбрасывает ее на диск
+func (l *Log) Record(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("Log.Record.Marshal: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Запись одной строкой одним вызовом, чтобы строки не перемешивались
+	_, err = l.file.Write(append(data, '\n'))
+	if err != nil {
+		return fmt.Errorf("Log.Record.Write: %w", err)
+	}
+
+	err = l.file.Sync()
+	if err != nil {
+		return fmt.Errorf("Log.Record.Sync: %w", err)
+	}
+
+	return nil
+}
+
+// Last возвращает последние n записей журнала от старых к новым, поврежденные строки пропускаются.
+// Файл читается с конца блоками, чтобы время чтения не зависело от размера журнала
+func (l *Log) Last(n int) ([]Entry, error) {
+	if n <= 0 {
+		return []Entry{}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("Log.Last.Open: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("Log.Last.Stat: %w", err)
+	}
+
+	entries := make([]Entry, 0, n)
+	add := func(line []byte) {
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	// rest начало строки, которая продолжается в уже прочитанной части файла
+	var rest []byte
+	offset := stat.Size()
+	for offset > 0 && len(entries) < n {
+		size := min(int64(readBlockSize), offset)
+		offset -= size
+
+		block := make([]byte, size, int(size)+len(rest))
+		_, err := file.ReadAt(block, offset)
+		if err != nil {
+			return nil, fmt.Errorf("Log.Last.ReadAt: %w", err)
+		}
+		block = append(block, rest...)
+
+		for len(entries) < n {
+			i := bytes.LastIndexByte(block, '\n')
+			if i < 0 {
+				break
+			}
+
+			add(block[i+1:])
+			block = block[:i]
+		}
+
+		rest = block
+		// Слишком длинная строка считается поврежденной, ее начало тоже не разберется как запись
+		if len(rest) > maxEntrySize {
+			rest = nil
+		}
+	}
+
+	if offset == 0 && len(entries) < n && len(rest) > 0 {
+		add(rest)
+	}
+
+	slices.Reverse(entries)
+
+	return entries, nil
+}
+
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.file.Close()
+}
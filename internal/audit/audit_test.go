@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLastReadsFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer log.Close()
+
+	// Записей больше, чем помещается в один блок чтения
+	const total = 3000
+	for i := range total {
+		if err := log.Record(Entry{Action: ActionNext, Target: fmt.Sprintf("video-%d", i), Result: ResultOK}); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	entries, err := log.Last(5)
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+
+	if len(entries) != 5 {
+		t.Fatalf("len(entries) = %d, want 5", len(entries))
+	}
+	for i, entry := range entries {
+		if want := fmt.Sprintf("video-%d", total-5+i); entry.Target != want {
+			t.Fatalf("entries[%d].Target = %s, want %s", i, entry.Target, want)
+		}
+	}
+}
+
+func TestLastSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	content := `{"action":"start","result":"ok"}` + "\n" +
+		"not json\n" +
+		`{"action":"stop","result":"ok"}` + "\n" +
+		strings.Repeat("x", maxEntrySize+1) + "\n" +
+		`{"action":"next","result":"ok"}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	log, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer log.Close()
+
+	entries, err := log.Last(10)
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+
+	var actions []Action
+	for _, entry := range entries {
+		actions = append(actions, entry.Action)
+	}
+	if fmt.Sprint(actions) != fmt.Sprint([]Action{ActionStart, ActionStop, ActionNext}) {
+		t.Fatalf("actions = %v, want [start stop next]", actions)
+	}
+}
@@ -0,0 +1,189 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+// commandRoles минимальная роль для команд из updateCommand, остальные команды доступны только owner
+var commandRoles = map[string]config.BotRole{
+	"/start":                     config.BotRoleViewer,
+	string(ButtonTypeStatistics): config.BotRoleViewer,
+	// Роль для ключей и поиска проверяется в handleInline, остальным показывается подсказка
+	"inline": config.BotRoleViewer,
+
+	string(ButtonTypeNext):                config.BotRoleOperator,
+	string(ButtonTypeQueue):               config.BotRoleOperator,
+	string(ButtonTypeReloadList):          config.BotRoleOperator,
+	string(ButtonTypeLibrary):             config.BotRoleOperator,
+	QueueVideoMessagePrefix:               config.BotRoleOperator,
+	"callback:" + NextVideoCallbackPrefix: config.BotRoleOperator,
+	"callback:" + QueueCallbackPrefix:     config.BotRoleOperator,
+	// Библиотеку operator только просматривает, удаление и изменение видео, ответы с новыми данными видео ("message")
+	// и загрузка видео доступны только owner
+	"callback:" + LibraryPageCallback: config.BotRoleOperator,
+	"callback:" + LibraryFileCallback: config.BotRoleOperator,
+}
+
+const (
+	// deniedUserInterval как часто отказ одному постороннему пользователю записывается в журналы
+	deniedUserInterval = time.Minute
+	deniedUsersLimit   = 1000
+)
+
+type roleContextKey struct{}
+
+// deniedUsers помнит, когда посторонним пользователям последний раз записывался отказ, чтобы сообщения
+// от них не раздували журнал аудита. Число пользователей ограничено, сверх него отказы не записываются
+type deniedUsers struct {
+	mu sync.Mutex
+
+	interval time.Duration
+	limit    int
+	now      func() time.Time
+	recorded map[int64]time.Time
+}
+
+func newDeniedUsers(interval time.Duration, limit int) *deniedUsers {
+	return &deniedUsers{
+		interval: interval,
+		limit:    limit,
+		now:      time.Now,
+		recorded: map[int64]time.Time{},
+	}
+}
+
+// Allow сообщает, нужно ли записать отказ пользователю, и запоминает время записи
+func (d *deniedUsers) Allow(userID int64) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	if recorded, ok := d.recorded[userID]; ok && now.Sub(recorded) < d.interval {
+		return false
+	}
+
+	if len(d.recorded) >= d.limit {
+		for id, recorded := range d.recorded {
+			if now.Sub(recorded) >= d.interval {
+				delete(d.recorded, id)
+			}
+		}
+		if len(d.recorded) >= d.limit {
+			return false
+		}
+	}
+
+	d.recorded[userID] = now
+	return true
+}
+
+func requiredRole(command string) config.BotRole {
+	if role, ok := commandRoles[command]; ok {
+		return role
+	}
+
+	return config.BotRoleOwner
+}
+
+// contextRole роль пользователя, от которого пришло обновление
+func contextRole(ctx context.Context) config.BotRole {
+	role, _ := ctx.Value(roleContextKey{}).(config.BotRole)
+	return role
+}
+
+// accessMiddleware пропускает к обработчикам только обновления от пользователей бота с ролью, достаточной для команды
+func (s *streamBot) accessMiddleware(next telegramBot.HandlerFunc) telegramBot.HandlerFunc {
+	return func(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+		logger := zerolog.Ctx(ctx)
+
+		user := updateUser(update)
+		if user == nil {
+			return
+		}
+
+		command := updateCommand(update)
+		role, ok := s.Users[user.ID]
+		if !ok {
+			if s.DeniedUsers.Allow(user.ID) {
+				logger.Warn().
+					Int64("user", user.ID).
+					Str("command", command).
+					Msg("Access denied for unknown user")
+				s.audit(ctx, user, audit.Entry{Action: audit.Action(command), Result: audit.ResultDenied})
+			}
+
+			// На сообщения от посторонних бот не отвечает
+			if update.Message == nil {
+				denyAccess(ctx, b, update, "Для получения доступа обратитесь к владельцу")
+			}
+			return
+		}
+
+		if !role.Allows(requiredRole(command)) {
+			logger.Warn().
+				Int64("user", user.ID).
+				Str("role", string(role)).
+				Str("command", command).
+				Msg("Access denied for user role")
+			s.audit(ctx, user, audit.Entry{Action: audit.Action(command), Result: audit.ResultDenied})
+
+			denyAccess(ctx, b, update, "Недостаточно прав для этого действия")
+			return
+		}
+
+		next(context.WithValue(ctx, roleContextKey{}, role), b, update)
+	}
+}
+
+func updateUser(update *models.Update) *models.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return &update.CallbackQuery.From
+	case update.InlineQuery != nil:
+		return update.InlineQuery.From
+	default:
+		return nil
+	}
+}
+
+func denyAccess(ctx context.Context, b *telegramBot.Bot, update *models.Update, text string) {
+	switch {
+	case update.Message != nil:
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "⛔️ " + text,
+		})
+	case update.CallbackQuery != nil:
+		b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
+			CallbackQueryID: update.CallbackQuery.ID,
+			ShowAlert:       true,
+			Text:            text,
+		})
+	case update.InlineQuery != nil:
+		b.AnswerInlineQuery(ctx, &telegramBot.AnswerInlineQueryParams{
+			InlineQueryID: update.InlineQuery.ID,
+			IsPersonal:    true,
+			CacheTime:     1,
+			Results: []models.InlineQueryResult{
+				&models.InlineQueryResultArticle{
+					ID:          "none",
+					Title:       "Доступ запрещен",
+					Description: text,
+					InputMessageContent: &models.InputTextMessageContent{
+						MessageText: "Error",
+					},
+				},
+			},
+		})
+	}
+}
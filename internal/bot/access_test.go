@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	testViewer   = 10
+	testOperator = 20
+	testOwner    = 30
+	testStranger = 40
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		command string
+		role    config.BotRole
+		allowed bool
+	}{
+		{command: string(ButtonTypeStatistics), role: config.BotRoleViewer, allowed: true},
+		{command: string(ButtonTypeNext), role: config.BotRoleViewer, allowed: false},
+		{command: string(ButtonTypeNext), role: config.BotRoleOperator, allowed: true},
+		{command: string(ButtonTypeStart), role: config.BotRoleOperator, allowed: false},
+		{command: string(ButtonTypeStart), role: config.BotRoleOwner, allowed: true},
+		{command: "callback:" + LibraryFileCallback, role: config.BotRoleOperator, allowed: true},
+		{command: "callback:" + LibraryDeleteCallback, role: config.BotRoleOperator, allowed: false},
+		{command: "callback:" + LibraryEditCallback, role: config.BotRoleOperator, allowed: false},
+		// Загрузка видео и ответы с новыми данными видео приходят обычными сообщениями
+		{command: "message", role: config.BotRoleOperator, allowed: false},
+		{command: "message", role: config.BotRoleOwner, allowed: true},
+		{command: "inline", role: config.BotRoleViewer, allowed: true},
+		{command: "/audit", role: config.BotRoleOperator, allowed: false},
+		// Неизвестная роль не дает никаких прав
+		{command: string(ButtonTypeStatistics), role: config.BotRole("admin"), allowed: false},
+	}
+
+	for _, test := range tests {
+		if got := test.role.Allows(requiredRole(test.command)); got != test.allowed {
+			t.Errorf("%s allowed for %s = %v, want %v", test.command, test.role, got, test.allowed)
+		}
+	}
+}
+
+func TestAccessMiddleware(t *testing.T) {
+	message := func(user int64, text string) *models.Update {
+		return &models.Update{Message: &models.Message{
+			From: &models.User{ID: user},
+			Chat: models.Chat{ID: user},
+			Text: text,
+		}}
+	}
+	callback := func(user int64, data string) *models.Update {
+		return &models.Update{CallbackQuery: &models.CallbackQuery{ID: "1", From: models.User{ID: user}, Data: data}}
+	}
+	inline := func(user int64) *models.Update {
+		return &models.Update{InlineQuery: &models.InlineQuery{ID: "1", From: &models.User{ID: user}, Query: "q video"}}
+	}
+
+	tests := []struct {
+		name    string
+		update  *models.Update
+		allowed bool
+		// answer метод Bot API, которым бот сообщает об отказе, пустой если бот не отвечает
+		answer string
+	}{
+		{name: "viewer statistics", update: message(testViewer, string(ButtonTypeStatistics)), allowed: true},
+		{name: "viewer next", update: message(testViewer, string(ButtonTypeNext)), answer: "sendMessage"},
+		{name: "operator next", update: message(testOperator, string(ButtonTypeNext)), allowed: true},
+		{name: "operator stop", update: message(testOperator, string(ButtonTypeStop)), answer: "sendMessage"},
+		{name: "operator library file", update: callback(testOperator, LibraryFileCallback+":1"), allowed: true},
+		{name: "operator library delete", update: callback(testOperator, LibraryDeleteCallback+":1"), answer: "answerCallbackQuery"},
+		{name: "operator upload", update: message(testOperator, ""), answer: "sendMessage"},
+		{name: "owner library delete", update: callback(testOwner, LibraryDeleteCallback+":1"), allowed: true},
+		{name: "stranger message", update: message(testStranger, "/start")},
+		{name: "stranger callback", update: callback(testStranger, NextVideoCallbackPrefix), answer: "answerCallbackQuery"},
+		{name: "stranger inline", update: inline(testStranger), answer: "answerInlineQuery"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			api := &fakeTelegramAPI{}
+			apiServer := httptest.NewServer(api)
+			defer apiServer.Close()
+
+			b, err := telegramBot.New(testBotToken, telegramBot.WithServerURL(apiServer.URL), telegramBot.WithSkipGetMe())
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+
+			s := &streamBot{
+				Users: map[int64]config.BotRole{
+					testViewer:   config.BotRoleViewer,
+					testOperator: config.BotRoleOperator,
+					testOwner:    config.BotRoleOwner,
+				},
+				DeniedUsers: newDeniedUsers(deniedUserInterval, deniedUsersLimit),
+			}
+
+			called := false
+			next := func(ctx context.Context, _ *telegramBot.Bot, _ *models.Update) {
+				called = true
+				if contextRole(ctx) == "" {
+					t.Error("role is not passed to handler")
+				}
+			}
+
+			s.accessMiddleware(next)(context.Background(), b, test.update)
+
+			if called != test.allowed {
+				t.Fatalf("handler called = %v, want %v", called, test.allowed)
+			}
+
+			for _, method := range []string{"sendMessage", "answerCallbackQuery", "answerInlineQuery"} {
+				if got, want := api.called(method), method == test.answer; got != want {
+					t.Errorf("%s called = %v, want %v", method, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeniedUsersAllowOncePerInterval(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	denied := newDeniedUsers(time.Minute, 2)
+	denied.now = func() time.Time { return now }
+
+	if !denied.Allow(1) {
+		t.Fatal("first denial of user 1 is not recorded")
+	}
+	if denied.Allow(1) {
+		t.Fatal("repeated denial of user 1 is recorded within interval")
+	}
+	if !denied.Allow(2) {
+		t.Fatal("first denial of user 2 is not recorded")
+	}
+	// Пользователей уже столько, сколько помещается
+	if denied.Allow(3) {
+		t.Fatal("denial of user 3 is recorded over limit")
+	}
+
+	now = now.Add(time.Minute)
+	if !denied.Allow(3) {
+		t.Fatal("denial of user 3 is not recorded after old users expired")
+	}
+	if !denied.Allow(1) {
+		t.Fatal("denial of user 1 is not recorded after interval")
+	}
+}
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// AuditCommand показывает последние записи журнала аудита: /audit или /audit 50
+	AuditCommand = "/audit"
+
+	defaultAuditEntries = 20
+	maxAuditEntries     = 100
+	// maxAuditMessageLength ограничение Telegram на длину сообщения с запасом
+	maxAuditMessageLength = 4000
+	maxAuditErrorLength   = 100
+)
+
+// auditAction записывает действие пользователя в журнал аудита, err nil означает успешное действие
+func (s *streamBot) auditAction(ctx context.Context, user *models.User, action audit.Action, target string, err error) {
+	s.audit(ctx, user, audit.NewEntry(action, target, err))
+}
+
+func (s *streamBot) audit(ctx context.Context, user *models.User, entry audit.Entry) {
+	if s.Audit == nil {
+		return
+	}
+
+	entry.UserID = user.ID
+	entry.Username = user.Username
+
+	err := s.Audit.Record(entry)
+	if err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("action", string(entry.Action)).Msg("Unable to write audit log")
+	}
+}
+
+func (s *streamBot) handleAudit(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Handle audit log")
+
+	if s.Audit == nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Журнал аудита не настроен",
+		})
+		return
+	}
+
+	count := defaultAuditEntries
+	if fields := strings.Fields(update.Message.Text); len(fields) > 1 {
+		if n, err := strconv.Atoi(fields[1]); err == nil && n > 0 {
+			count = min(n, maxAuditEntries)
+		}
+	}
+
+	entries, err := s.Audit.Last(count)
+	if err != nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Не удалось прочитать журнал аудита:\n%v", err),
+		})
+		return
+	}
+
+	if len(entries) == 0 {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Журнал аудита пуст",
+		})
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, auditLine(entry))
+	}
+
+	// Если записи не помещаются в одно сообщение, то показываем самые новые
+	length := 0
+	first := len(lines)
+	for first > 0 && length+len(lines[first-1])+1 <= maxAuditMessageLength {
+		first--
+		length += len(lines[first]) + 1
+	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   "🧾 Последние действия:\n\n" + strings.Join(lines[first:], "\n"),
+	})
+}
+
+func auditLine(entry audit.Entry) string {
+	user := strconv.FormatInt(entry.UserID, 10)
+	switch {
+	// Действия через HTTP API и по расписанию записываются без пользователя телеграмма
+	case entry.UserID == 0 && entry.Username != "":
+		user = entry.Username
+	case entry.Username != "":
+		user = fmt.Sprintf("@%s (%d)", entry.Username, entry.UserID)
+	}
+
+	line := fmt.Sprintf("%s %s %s", entry.Time.Local().Format("02.01 15:04:05"), user, entry.Action)
+	if entry.Target != "" {
+		line += " " + entry.Target
+	}
+	line += " - " + string(entry.Result)
+
+	if entry.Error != "" {
+		errorText := []rune(entry.Error)
+		if len(errorText) > maxAuditErrorLength {
+			errorText = append(errorText[:maxAuditErrorLength], '…')
+		}
+		line += ": " + string(errorText)
+	}
+
+	return line
+}
@@ -3,7 +3,11 @@ package bot
 import (
 	"context"
 
-	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/keys"
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+	"github.com/Perkovec/StatiStream/internal/service"
 	"github.com/Perkovec/StatiStream/internal/stream"
 	telegramBot "github.com/go-telegram/bot"
 	"github.com/rs/zerolog"
@@ -18,33 +22,74 @@ const (
 	ButtonTypeStart      ButtonType = "🟢 Запустить"
 	ButtonTypeStop       ButtonType = "⛔️ Остановить"
 	ButtonTypeQueue      ButtonType = "📄 Очередь"
+	ButtonTypeLibrary    ButtonType = "🗂 Библиотека"
 )
 
 type streamBot struct {
-	AcceptedUsers []int64
-	VideoStorage  storage.Storage
-	Streams       stream.Streams
-
-	StreamTokensMap map[string]string
+	// Users роли пользователей бота, обновления от остальных пользователей не обрабатываются
+	Users   map[int64]config.BotRole
+	Service *service.Service
+	Streams stream.Streams
+	Vault   *keys.Vault
+	// Audit журнал действий управления, nil если не настроен
+	Audit *audit.Log
+	// DeniedUsers ограничивает записи об отказах посторонним пользователям
+	DeniedUsers *deniedUsers
+
+	// Ключи из inline запросов, ожидающие выбора платформы
+	StreamTokens *pendingTokens
+	// Установленные ключи, ожидающие решения о сохранении в хранилище
+	VaultTokens *pendingTokens
+	// Upload загрузка видео через бота, nil если выключена
+	Upload *UploadParams
+	// Изменения видео в библиотеке, ожидающие ответа с новым значением
+	LibraryEdits *libraryEdits
 }
 
 type BotParams struct {
-	AcceptedUsers []int64
-	Token         string
-	VideoStorage  storage.Storage
-	Streams       stream.Streams
+	Users   map[int64]config.BotRole
+	Token   string
+	Service *service.Service
+	Streams stream.Streams
+	Vault   *keys.Vault
+	// Status необязательно, в него сообщаются ошибки получения обновлений для проверки готовности
+	Status *monitoring.BotStatus
+	// APIServer необязательно, адрес локального сервера Bot API
+	APIServer string
+	// Upload необязательно, загрузка видео через бота
+	Upload *UploadParams
+	// Audit необязательно, журнал действий управления
+	Audit *audit.Log
 }
 
 func NewBot(ctx context.Context, cfg BotParams) (*telegramBot.Bot, error) {
 	streamBot := &streamBot{
-		AcceptedUsers:   cfg.AcceptedUsers,
-		VideoStorage:    cfg.VideoStorage,
-		Streams:         cfg.Streams,
-		StreamTokensMap: map[string]string{},
+		Users:        cfg.Users,
+		Service:      cfg.Service,
+		Streams:      cfg.Streams,
+		Vault:        cfg.Vault,
+		StreamTokens: newPendingTokens(pendingTokenTTL, pendingTokensLimit),
+		VaultTokens:  newPendingTokens(pendingTokenTTL, pendingTokensLimit),
+		Upload:       cfg.Upload,
+		LibraryEdits: newLibraryEdits(),
+		Audit:        cfg.Audit,
+		DeniedUsers:  newDeniedUsers(deniedUserInterval, deniedUsersLimit),
 	}
 
 	opts := []telegramBot.Option{
 		telegramBot.WithDefaultHandler(streamBot.handleInline),
+		telegramBot.WithMiddlewares(metricsMiddleware, streamBot.accessMiddleware),
+	}
+
+	if len(cfg.APIServer) > 0 {
+		opts = append(opts, telegramBot.WithServerURL(cfg.APIServer))
+	}
+
+	if cfg.Status != nil {
+		opts = append(opts, telegramBot.WithErrorsHandler(func(err error) {
+			cfg.Status.ReportError()
+			zerolog.Ctx(ctx).Error().Err(err).Msg("Telegram bot error")
+		}))
 	}
 
 	b, err := telegramBot.New(cfg.Token, opts...)
@@ -54,32 +99,33 @@ func NewBot(ctx context.Context, cfg BotParams) (*telegramBot.Bot, error) {
 
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, "/start", telegramBot.MatchTypeExact, streamBot.handleStart)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeReloadList), telegramBot.MatchTypeExact, streamBot.handleReloadStorage)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeStatistics), telegramBot.MatchTypeExact, streamBot.handleStatistics)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeNext), telegramBot.MatchTypeExact, streamBot.preHandleNextVideo)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeStart), telegramBot.MatchTypeExact, streamBot.preStartStream)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeStop), telegramBot.MatchTypeExact, streamBot.preStopStream)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeQueue), telegramBot.MatchTypeExact, streamBot.handleQueue)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, string(ButtonTypeLibrary), telegramBot.MatchTypeExact, streamBot.handleLibrary)
+	b.RegisterHandlerMatchFunc(streamBot.isLibraryEditReply, streamBot.handleLibraryEditReply)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, QueueVideoMessagePrefix, telegramBot.MatchTypePrefix, streamBot.handleQueueVideoMessage)
 	b.RegisterHandler(telegramBot.HandlerTypeMessageText, "stream_key:", telegramBot.MatchTypePrefix, streamBot.handleSetStreamKey)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, UnlockVaultCommand, telegramBot.MatchTypePrefix, streamBot.handleUnlockVault)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, ForgetKeysCommand, telegramBot.MatchTypeExact, streamBot.preForgetKeys)
+	b.RegisterHandler(telegramBot.HandlerTypeMessageText, AuditCommand, telegramBot.MatchTypePrefix, streamBot.handleAudit)
+
+	if cfg.Upload != nil {
+		b.RegisterHandlerMatchFunc(isVideoUpload, streamBot.handleUpload)
+	}
 
 	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, NextVideoCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleNextVideo)
 	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, StartStreamCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleStartStream)
+	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, StopStreamCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleStopStream)
 	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, QueueCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleAddVideoQueue)
+	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, LibraryCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleLibraryCallback)
+	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, VaultSaveCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleVaultSave)
+	b.RegisterHandler(telegramBot.HandlerTypeCallbackQueryData, ForgetKeysCallbackPrefix, telegramBot.MatchTypePrefix, streamBot.handleForgetKeys)
 
-	go streamBot.captureNextVideo(ctx)
+	go streamBot.StreamTokens.Run(ctx)
+	go streamBot.VaultTokens.Run(ctx)
 
 	return b, nil
 }
-
-func (s *streamBot) captureNextVideo(ctx context.Context) {
-	logger := zerolog.Ctx(ctx)
-
-	for {
-	out:
-		for _, stream := range s.Streams {
-			<-stream.NextVideo()
-			video, contentLength, videoMeta := s.VideoStorage.GetNextVideo()
-			logger.Info().Msgf("Start video \"%s\": %d\n", videoMeta.Filename, contentLength)
-			stream.SetVideo(video, contentLength)
-			break out
-		}
-	}
-}
@@ -2,13 +2,15 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"slices"
 	"strings"
 
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/service"
 	telegramBot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
-	gonanoid "github.com/matoous/go-nanoid/v2"
 	"github.com/rs/zerolog"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -16,6 +18,14 @@ import (
 
 const (
 	TokenAcceptPrefix = "token_"
+	// InlineKeyCommand inline запрос с ключом трансляции: @bot key <ключ>
+	InlineKeyCommand = "key"
+	// InlineSearchCommand inline поиск видео для очереди: @bot q <текст>
+	InlineSearchCommand = "q"
+	// QueueVideoMessagePrefix начало сообщения, которое отправляется при выборе видео в inline поиске
+	QueueVideoMessagePrefix = "📄 В очередь: "
+
+	inlineSearchLimit = 20
 )
 
 func (s *streamBot) handleInline(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
@@ -25,114 +35,228 @@ func (s *streamBot) handleInline(ctx context.Context, b *telegramBot.Bot, update
 		return
 	}
 
-	if slices.Contains(s.AcceptedUsers, update.InlineQuery.From.ID) {
-		logger.Info().
-			Int64("user", update.InlineQuery.From.ID).
-			Msgf("Handle inline query")
+	// Запрос разбирается по первому слову, чтобы обычный текст не принимался за ключ трансляции
+	command, argument, _ := strings.Cut(strings.TrimSpace(update.InlineQuery.Query), " ")
+	argument = strings.TrimSpace(argument)
 
-		temporaryKey, err := gonanoid.New()
-		if err != nil {
-			logger.Err(err)
-
-			b.AnswerInlineQuery(ctx, &telegramBot.AnswerInlineQueryParams{
-				InlineQueryID: update.InlineQuery.ID,
-				IsPersonal:    true,
-				CacheTime:     1,
-				Results: []models.InlineQueryResult{
-					&models.InlineQueryResultArticle{
-						ID:          "error",
-						Title:       "Ошибка",
-						Description: err.Error(),
-						InputMessageContent: &models.InputTextMessageContent{
-							MessageText: "Error",
-						},
-					},
-				},
-			})
-			return
-		}
+	logger.Info().
+		Int64("user", update.InlineQuery.From.ID).
+		Str("command", command).
+		Msgf("Handle inline query")
+
+	role := contextRole(ctx)
 
-		s.StreamTokensMap[temporaryKey] = update.InlineQuery.Query
+	var results []models.InlineQueryResult
+	switch {
+	case command == InlineKeyCommand && argument != "" && role.Allows(config.BotRoleOwner):
+		results = s.streamKeyResults(ctx, update.InlineQuery.From.ID, argument)
+	case command == InlineSearchCommand && argument != "" && role.Allows(config.BotRoleOperator):
+		results = s.searchResults(argument)
+	default:
+		results = inlineHelpResults(role)
+	}
+
+	b.AnswerInlineQuery(ctx, &telegramBot.AnswerInlineQueryParams{
+		InlineQueryID: update.InlineQuery.ID,
+		IsPersonal:    true,
+		CacheTime:     1,
+		Results:       results,
+	})
+}
 
-		results := make([]models.InlineQueryResult, 0, len(s.Streams))
-		for platform := range s.Streams {
-			platformName := cases.Title(language.Russian, cases.Compact).String(string(platform))
-			results = append(results, &models.InlineQueryResultArticle{
-				ID:          TokenAcceptPrefix + string(platform),
-				Title:       platformName,
-				Description: fmt.Sprintf("Применить ключ трансляции для платформы %s", platformName),
+// inlineHelpResults подсказка по inline запросам, доступным роли пользователя
+func inlineHelpResults(role config.BotRole) []models.InlineQueryResult {
+	lines := []string{}
+	if role.Allows(config.BotRoleOperator) {
+		lines = append(lines, InlineSearchCommand+" <текст> - найти видео и добавить в очередь")
+	}
+	if role.Allows(config.BotRoleOwner) {
+		lines = append(lines, InlineKeyCommand+" <ключ> - установить ключ трансляции")
+	}
+
+	if len(lines) == 0 {
+		return []models.InlineQueryResult{}
+	}
+
+	text := strings.Join(lines, "\n")
+
+	return []models.InlineQueryResult{
+		&models.InlineQueryResultArticle{
+			ID:          "help",
+			Title:       "Как пользоваться",
+			Description: text,
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText: text,
+			},
+		},
+	}
+}
+
+// streamKeyResults сохраняет ключ под временным идентификатором и предлагает выбрать платформу для него
+func (s *streamBot) streamKeyResults(ctx context.Context, user int64, key string) []models.InlineQueryResult {
+	logger := zerolog.Ctx(ctx)
+
+	temporaryKey, err := s.StreamTokens.Put(user, key)
+	if err != nil {
+		logger.Err(err)
+
+		return []models.InlineQueryResult{
+			&models.InlineQueryResultArticle{
+				ID:          "error",
+				Title:       "Ошибка",
+				Description: err.Error(),
 				InputMessageContent: &models.InputTextMessageContent{
-					MessageText: fmt.Sprintf("stream_key:%s:%s", platform, temporaryKey),
+					MessageText: "Error",
 				},
-			})
+			},
 		}
+	}
 
-		b.AnswerInlineQuery(ctx, &telegramBot.AnswerInlineQueryParams{
-			InlineQueryID: update.InlineQuery.ID,
-			IsPersonal:    true,
-			CacheTime:     1,
-			Results:       results,
+	platforms := s.Service.Platforms()
+	results := make([]models.InlineQueryResult, 0, len(platforms))
+	for _, platform := range platforms {
+		platformName := cases.Title(language.Russian, cases.Compact).String(string(platform))
+		results = append(results, &models.InlineQueryResultArticle{
+			ID:          TokenAcceptPrefix + string(platform),
+			Title:       platformName,
+			Description: fmt.Sprintf("Применить ключ трансляции для платформы %s", platformName),
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText: fmt.Sprintf("stream_key:%s:%s", platform, temporaryKey),
+			},
 		})
-	} else {
-		b.AnswerInlineQuery(ctx, &telegramBot.AnswerInlineQueryParams{
-			InlineQueryID: update.InlineQuery.ID,
-			IsPersonal:    true,
-			CacheTime:     1,
-			Results: []models.InlineQueryResult{
-				&models.InlineQueryResultArticle{
-					ID:          "none",
-					Title:       "Доступ запрещен",
-					Description: "Для получения доступа обратитесь к владельцу",
-					InputMessageContent: &models.InputTextMessageContent{
-						MessageText: "Error",
-					},
-				},
+	}
+
+	return results
+}
+
+// searchResults видео из библиотеки, подходящие под запрос, при выборе видео в чат отправляется сообщение,
+// по которому бот добавляет его в очередь
+func (s *streamBot) searchResults(query string) []models.InlineQueryResult {
+	files := s.Service.SearchLibrary(query, inlineSearchLimit)
+	results := make([]models.InlineQueryResult, 0, len(files))
+	for _, file := range files {
+		title := file.Meta.Title
+		if title == "" {
+			title = file.Key
+		}
+
+		description := file.Key
+		if file.Meta.Category != "" {
+			description += " · " + file.Meta.Category
+		}
+
+		results = append(results, &models.InlineQueryResultArticle{
+			ID:          libraryFileID(file.Key),
+			Title:       title,
+			Description: description,
+			InputMessageContent: &models.InputTextMessageContent{
+				MessageText: QueueVideoMessagePrefix + file.Key,
 			},
 		})
 	}
+
+	return results
+}
+
+func (s *streamBot) handleQueueVideoMessage(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	key := strings.TrimPrefix(update.Message.Text, QueueVideoMessagePrefix)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Str("file", key).
+		Msgf("Handle queue video from search")
+
+	err := s.Service.AddToQueue(key)
+	s.auditAction(ctx, update.Message.From, audit.ActionQueueAdd, key, err)
+	if err != nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Не удалось добавить видео в очередь:\n%v", err),
+		})
+		return
+	}
+
+	text := "Очередь для транслирования:\n\n"
+	for _, item := range s.Service.Queue() {
+		text += fmt.Sprintf("- %s\n", item)
+	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   text,
+	})
 }
 
 func (s *streamBot) handleSetStreamKey(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
 	logger := zerolog.Ctx(ctx)
 
-	if slices.Contains(s.AcceptedUsers, update.Message.From.ID) {
-		logger.Info().
-			Int64("user", update.Message.From.ID).
-			Msgf("Handle set stream key")
-
-		parts := strings.Split(update.Message.Text, ":")
-		if len(parts) != 3 {
-			b.SendMessage(ctx, &telegramBot.SendMessageParams{
-				ChatID: update.Message.Chat.ID,
-				Text:   "Не удалось распарсить данные",
-			})
-			return
-		}
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Handle set stream key")
 
-		originalToken, ok := s.StreamTokensMap[parts[2]]
-		if !ok {
-			b.SendMessage(ctx, &telegramBot.SendMessageParams{
-				ChatID: update.Message.Chat.ID,
-				Text:   "Ключ не найден",
-			})
-			return
-		}
+	parts := strings.Split(update.Message.Text, ":")
+	if len(parts) != 3 {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Не удалось распарсить данные",
+		})
+		return
+	}
 
-		for platform, stream := range s.Streams {
-			if string(platform) == parts[1] {
-				stream.SetStreamToken(originalToken)
+	// Сообщение с идентификатором ключа больше не нужно, убираем его из чата
+	b.DeleteMessage(ctx, &telegramBot.DeleteMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		MessageID: update.Message.ID,
+	})
 
-				b.SendMessage(ctx, &telegramBot.SendMessageParams{
-					ChatID: update.Message.Chat.ID,
-					Text:   fmt.Sprintf("Ключ трансляции для платформы %s установлен", platform),
-				})
-				return
-			}
-		}
+	originalToken, ok := s.StreamTokens.Take(parts[2], update.Message.From.ID)
+	if !ok {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Ключ не найден или устарел, введите его заново",
+		})
+		return
+	}
 
+	platform := config.Platform(parts[1])
+	err := s.Service.SetStreamKey(platform, originalToken)
+	s.auditAction(ctx, update.Message.From, audit.ActionSetKey, string(platform), err)
+	switch {
+	case errors.Is(err, service.ErrUnknownPlatform):
 		b.SendMessage(ctx, &telegramBot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
 			Text:   fmt.Sprintf("Неизвестная платформа: %s", parts[1]),
 		})
+		return
+	case err != nil:
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Не удалось установить ключ трансляции:\n%v", err),
+		})
+		return
+	}
+
+	msgParams := &telegramBot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("Ключ трансляции для платформы %s установлен", platform),
 	}
+
+	switch {
+	case s.Vault == nil:
+	case s.Vault.IsUnlocked():
+		vaultKey, err := s.VaultTokens.Put(update.Message.From.ID, originalToken)
+		if err != nil {
+			logger.Err(err).Msg("Unable to offer saving key to vault")
+			break
+		}
+
+		msgParams.Text += "\nСохранить его в зашифрованное хранилище, чтобы не вводить после перезапуска?"
+		msgParams.ReplyMarkup = vaultSaveKeyboard(platform, vaultKey)
+	default:
+		msgParams.Text += fmt.Sprintf("\nЧтобы сохранять ключи в зашифрованное хранилище, разблокируйте его командой %s <пароль>", UnlockVaultCommand)
+	}
+
+	b.SendMessage(ctx, msgParams)
 }
@@ -0,0 +1,235 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/keys"
+	"github.com/Perkovec/StatiStream/internal/service"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	UnlockVaultCommand = "/unlock"
+	ForgetKeysCommand  = "/forget_keys"
+)
+
+const (
+	VaultSaveCallbackPrefix  = "vault_save"
+	ApproveVaultSaveCallback = VaultSaveCallbackPrefix + "_approve"
+	CancelVaultSaveCallback  = VaultSaveCallbackPrefix + "_cancel"
+
+	ForgetKeysCallbackPrefix  = "forget_keys"
+	ApproveForgetKeysCallback = ForgetKeysCallbackPrefix + "_approve"
+	CancelForgetKeysCallback  = ForgetKeysCallbackPrefix + "_cancel"
+)
+
+// vaultSaveKeyboard предлагает сохранить только что установленный ключ в зашифрованное хранилище
+func vaultSaveKeyboard(platform config.Platform, temporaryKey string) models.InlineKeyboardMarkup {
+	return models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{
+					Text:         "💾 Сохранить",
+					CallbackData: fmt.Sprintf("%s:%s:%s", ApproveVaultSaveCallback, platform, temporaryKey),
+				},
+				{
+					Text:         "Не сохранять",
+					CallbackData: fmt.Sprintf("%s:%s:%s", CancelVaultSaveCallback, platform, temporaryKey),
+				},
+			},
+		},
+	}
+}
+
+func (s *streamBot) handleUnlockVault(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Handle unlock key vault")
+
+	// Сообщение содержит пароль от хранилища, поэтому удаляем его из чата
+	b.DeleteMessage(ctx, &telegramBot.DeleteMessageParams{
+		ChatID:    update.Message.Chat.ID,
+		MessageID: update.Message.ID,
+	})
+
+	if s.Vault == nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Хранилище ключей не настроено",
+		})
+		return
+	}
+
+	passphrase := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, UnlockVaultCommand))
+	if passphrase == "" {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Укажите пароль от хранилища: %s <пароль>", UnlockVaultCommand),
+		})
+		return
+	}
+
+	err := s.Vault.Unlock(passphrase)
+	s.auditAction(ctx, update.Message.From, audit.ActionUnlockVault, "", err)
+	if err != nil {
+		msgText := fmt.Sprintf("Не удалось разблокировать хранилище ключей:\n%v", err)
+		if errors.Is(err, keys.ErrWrongPassphrase) {
+			msgText = "Неверный пароль от хранилища ключей"
+		}
+
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   msgText,
+		})
+		return
+	}
+
+	applied, err := keys.ApplyVault(s.Streams, s.Vault)
+	if err != nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Не удалось загрузить ключи из хранилища:\n%v", err),
+		})
+		return
+	}
+
+	msgText := "Хранилище ключей разблокировано, сохраненных ключей нет"
+	if len(applied) > 0 {
+		msgText = fmt.Sprintf("Хранилище ключей разблокировано, установлены ключи для платформ: %s", service.JoinPlatforms(applied))
+	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   msgText,
+	})
+}
+
+func (s *streamBot) handleVaultSave(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	if update.CallbackQuery.Message.Message != nil {
+		logger.Info().
+			Int64("user", update.CallbackQuery.From.ID).
+			Msgf("Handle save key to vault")
+
+		parts := strings.Split(update.CallbackQuery.Data, ":")
+		if len(parts) != 3 {
+			logger.Warn().Msgf("Invalid callback data: %s", update.CallbackQuery.Data)
+			return
+		}
+
+		originalToken, ok := s.VaultTokens.Take(parts[2], update.CallbackQuery.From.ID)
+
+		var editText string
+		switch {
+		case parts[0] == CancelVaultSaveCallback:
+			editText = "Ключ не сохранен в хранилище"
+			s.audit(ctx, &update.CallbackQuery.From, audit.Entry{Action: audit.ActionSaveKey, Target: parts[1], Result: audit.ResultCancelled})
+		case !ok:
+			editText = "Ключ не найден или устарел, введите его заново"
+		case s.Vault == nil:
+			editText = "Хранилище ключей не настроено"
+		default:
+			err := s.Vault.Save(config.Platform(parts[1]), originalToken)
+			s.auditAction(ctx, &update.CallbackQuery.From, audit.ActionSaveKey, parts[1], err)
+			if err != nil {
+				editText = fmt.Sprintf("Не удалось сохранить ключ в хранилище:\n%v", err)
+			} else {
+				editText = fmt.Sprintf("Ключ трансляции для платформы %s сохранен в хранилище", parts[1])
+			}
+		}
+
+		b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+			MessageID: update.CallbackQuery.Message.Message.ID,
+			Text:      editText,
+			ReplyMarkup: models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{},
+			},
+		})
+	}
+}
+
+func (s *streamBot) preForgetKeys(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Prehandle forget keys")
+
+	if s.Vault == nil {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Хранилище ключей не настроено",
+		})
+		return
+	}
+
+	keyboard := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{
+					Text:         "Подтвердить",
+					CallbackData: ApproveForgetKeysCallback,
+				},
+				{
+					Text:         "Отмена",
+					CallbackData: CancelForgetKeysCallback,
+				},
+			},
+		},
+	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        "Вы точно уверены, что хотите удалить все сохраненные ключи трансляций?",
+		ReplyMarkup: keyboard,
+	})
+}
+
+func (s *streamBot) handleForgetKeys(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	if update.CallbackQuery.Message.Message != nil {
+		logger.Info().
+			Int64("user", update.CallbackQuery.From.ID).
+			Msgf("Handle forget keys")
+
+		editText := "Операция отменена"
+		if update.CallbackQuery.Data == ApproveForgetKeysCallback && s.Vault != nil {
+			err := s.Vault.Forget()
+			s.auditAction(ctx, &update.CallbackQuery.From, audit.ActionForgetKeys, "", err)
+			if err != nil {
+				editText = fmt.Sprintf("Не удалось удалить хранилище ключей:\n%v", err)
+			} else {
+				editText = "Сохраненные ключи удалены, уже установленные ключи действуют до перезапуска сервиса"
+			}
+		}
+
+		b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+			MessageID: update.CallbackQuery.Message.Message.ID,
+			Text:      editText,
+			ReplyMarkup: models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{},
+			},
+		})
+	}
+}
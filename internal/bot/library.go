@@ -0,0 +1,469 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/service"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	LibraryCallbackPrefix        = "library"
+	LibraryPageCallback          = LibraryCallbackPrefix + "_page"
+	LibraryFileCallback          = LibraryCallbackPrefix + "_file"
+	LibraryDeleteCallback        = LibraryCallbackPrefix + "_delete"
+	LibraryApproveDeleteCallback = LibraryCallbackPrefix + "_approve_delete"
+	LibraryEditCallback          = LibraryCallbackPrefix + "_edit"
+)
+
+const (
+	libraryPageSize = 5
+	// libraryEditTTL сколько ждать ответа с новым значением после нажатия кнопки изменения
+	libraryEditTTL   = 10 * time.Minute
+	libraryEditLimit = 100
+)
+
+// libraryField что меняется ответом на сообщение бота
+type libraryField string
+
+const (
+	libraryFieldName     libraryField = "name"
+	libraryFieldTitle    libraryField = "title"
+	libraryFieldCategory libraryField = "category"
+	libraryFieldTags     libraryField = "tags"
+)
+
+var libraryFieldPrompts = map[libraryField]string{
+	libraryFieldName:     "Отправьте новое имя файла ответом на это сообщение, расширение можно не указывать",
+	libraryFieldTitle:    "Отправьте новое название ответом на это сообщение, \"-\" чтобы очистить",
+	libraryFieldCategory: "Отправьте новую категорию ответом на это сообщение, \"-\" чтобы очистить",
+	libraryFieldTags:     "Отправьте теги через запятую ответом на это сообщение, \"-\" чтобы очистить",
+}
+
+// libraryFileID короткий идентификатор видео для данных кнопок, ключ видео может не поместиться в 64 байта
+func libraryFileID(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:6])
+}
+
+type libraryEdit struct {
+	key       string
+	field     libraryField
+	expiresAt time.Time
+}
+
+// libraryEdits изменения, ожидающие ответа пользователя, по чату, пользователю, который нажал кнопку изменения,
+// и сообщению бота с запросом значения. В общем чате ответы других пользователей на запрос не принимаются
+type libraryEdits struct {
+	mu    sync.Mutex
+	now   func() time.Time
+	items map[string]libraryEdit
+}
+
+func newLibraryEdits() *libraryEdits {
+	return &libraryEdits{
+		now:   time.Now,
+		items: map[string]libraryEdit{},
+	}
+}
+
+func libraryEditID(chatID int64, userID int64, messageID int) string {
+	return fmt.Sprintf("%d:%d:%d", chatID, userID, messageID)
+}
+
+func (e *libraryEdits) Put(chatID int64, userID int64, messageID int, edit libraryEdit) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := e.now()
+	for id, item := range e.items {
+		if now.After(item.expiresAt) || len(e.items) >= libraryEditLimit {
+			delete(e.items, id)
+		}
+	}
+
+	edit.expiresAt = now.Add(libraryEditTTL)
+	e.items[libraryEditID(chatID, userID, messageID)] = edit
+}
+
+func (e *libraryEdits) Has(chatID int64, userID int64, messageID int) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, ok := e.items[libraryEditID(chatID, userID, messageID)]
+	return ok
+}
+
+func (e *libraryEdits) Take(chatID int64, userID int64, messageID int) (libraryEdit, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	id := libraryEditID(chatID, userID, messageID)
+	edit, ok := e.items[id]
+	delete(e.items, id)
+
+	if !ok || e.now().After(edit.expiresAt) {
+		return libraryEdit{}, false
+	}
+
+	return edit, true
+}
+
+func (s *streamBot) handleLibrary(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Handle library")
+
+	text, keyboard := s.libraryPage(1)
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        text,
+		ReplyMarkup: keyboard,
+	})
+}
+
+// libraryPage список видео библиотеки, включая исключенные, чтобы их можно было переименовать или удалить
+func (s *streamBot) libraryPage(page int) (string, models.InlineKeyboardMarkup) {
+	files := s.Service.Library()
+	keyboard := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{},
+	}
+
+	if len(files) == 0 {
+		return "В библиотеке нет видео", keyboard
+	}
+
+	pages := (len(files) + libraryPageSize - 1) / libraryPageSize
+	page = min(max(page, 1), pages)
+
+	start := (page - 1) * libraryPageSize
+	for _, file := range files[start:min(start+libraryPageSize, len(files))] {
+		text := file.Key
+		if file.Excluded {
+			text = "🚫 " + text
+		}
+
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, []models.InlineKeyboardButton{
+			{
+				Text:         text,
+				CallbackData: fmt.Sprintf("%s:%s", LibraryFileCallback, libraryFileID(file.Key)),
+			},
+		})
+	}
+
+	navigation := []models.InlineKeyboardButton{}
+	if page > 1 {
+		navigation = append(navigation, models.InlineKeyboardButton{
+			Text:         "◀️ Предыдущая",
+			CallbackData: fmt.Sprintf("%s:%d", LibraryPageCallback, page-1),
+		})
+	}
+	if page < pages {
+		navigation = append(navigation, models.InlineKeyboardButton{
+			Text:         "Следующая ▶️",
+			CallbackData: fmt.Sprintf("%s:%d", LibraryPageCallback, page+1),
+		})
+	}
+	if len(navigation) > 0 {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, navigation)
+	}
+
+	return fmt.Sprintf("Выберите видео (страница %d из %d):", page, pages), keyboard
+}
+
+// findLibraryFile ищет видео по короткому идентификатору, видео могло пропасть, пока открыто сообщение
+func (s *streamBot) findLibraryFile(id string) (storage.FileInfo, bool) {
+	for _, file := range s.Service.Library() {
+		if libraryFileID(file.Key) == id {
+			return file, true
+		}
+	}
+
+	return storage.FileInfo{}, false
+}
+
+// libraryFileCard карточка видео, кнопки изменения видео показываются только если manage
+func libraryFileCard(file storage.FileInfo, manage bool) (string, models.InlineKeyboardMarkup) {
+	id := libraryFileID(file.Key)
+
+	text := fmt.Sprintf("🎞 %s\n\nРазмер: %.1f МБ", file.Key, float64(file.Size)/1024/1024)
+	if file.Meta.Duration > 0 {
+		text += fmt.Sprintf("\nДлительность: %s", file.Meta.Duration.Round(time.Second))
+	}
+	text += fmt.Sprintf("\nНазвание: %s\nКатегория: %s\nТеги: %s",
+		valueOrDash(file.Meta.Title), valueOrDash(file.Meta.Category), valueOrDash(strings.Join(file.Meta.Tags, ", ")))
+	if file.Excluded {
+		text += fmt.Sprintf("\n\n🚫 Не воспроизводится: %s", file.Reason)
+	}
+
+	editButton := func(text string, field libraryField) models.InlineKeyboardButton {
+		return models.InlineKeyboardButton{
+			Text:         text,
+			CallbackData: fmt.Sprintf("%s:%s:%s", LibraryEditCallback, field, id),
+		}
+	}
+
+	backButton := models.InlineKeyboardButton{
+		Text:         "⬅️ К списку",
+		CallbackData: fmt.Sprintf("%s:%d", LibraryPageCallback, 1),
+	}
+
+	if !manage {
+		return text, models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{backButton}},
+		}
+	}
+
+	keyboard := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				editButton("✏️ Переименовать", libraryFieldName),
+				{
+					Text:         "🗑 Удалить",
+					CallbackData: fmt.Sprintf("%s:%s", LibraryDeleteCallback, id),
+				},
+			},
+			{
+				editButton("Название", libraryFieldTitle),
+				editButton("Категория", libraryFieldCategory),
+				editButton("Теги", libraryFieldTags),
+			},
+			{backButton},
+		},
+	}
+
+	return text, keyboard
+}
+
+// canManageLibrary может ли пользователь удалять и изменять видео в библиотеке
+func canManageLibrary(ctx context.Context) bool {
+	return contextRole(ctx).Allows(requiredRole("callback:" + LibraryEditCallback))
+}
+
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	return value
+}
+
+func (s *streamBot) handleLibraryCallback(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	action, value, _ := strings.Cut(update.CallbackQuery.Data, ":")
+	message := update.CallbackQuery.Message.Message
+	editMessage := func(text string, keyboard models.InlineKeyboardMarkup) {
+		b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:      message.Chat.ID,
+			MessageID:   message.ID,
+			Text:        text,
+			ReplyMarkup: keyboard,
+		})
+	}
+
+	logger.Info().
+		Int64("user", update.CallbackQuery.From.ID).
+		Str("action", action).
+		Msg("Handle library action")
+
+	if action == LibraryPageCallback {
+		page, _ := strconv.Atoi(value)
+		editMessage(s.libraryPage(page))
+		return
+	}
+
+	field, id := libraryField(""), value
+	if action == LibraryEditCallback {
+		var fieldValue string
+		fieldValue, id, _ = strings.Cut(value, ":")
+		field = libraryField(fieldValue)
+	}
+
+	file, ok := s.findLibraryFile(id)
+	if !ok {
+		editMessage("Видео не найдено, возможно его уже удалили или переименовали", models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text:         "⬅️ К списку",
+						CallbackData: fmt.Sprintf("%s:%d", LibraryPageCallback, 1),
+					},
+				},
+			},
+		})
+		return
+	}
+
+	switch action {
+	case LibraryFileCallback:
+		editMessage(libraryFileCard(file, canManageLibrary(ctx)))
+	case LibraryDeleteCallback:
+		editMessage(fmt.Sprintf("Вы точно уверены, что хотите удалить %s? Видео будет удалено из хранилища", file.Key), models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text:         "Подтвердить",
+						CallbackData: fmt.Sprintf("%s:%s", LibraryApproveDeleteCallback, id),
+					},
+					{
+						Text:         "Отмена",
+						CallbackData: fmt.Sprintf("%s:%s", LibraryFileCallback, id),
+					},
+				},
+			},
+		})
+	case LibraryApproveDeleteCallback:
+		err := s.Service.DeleteVideo(ctx, file.Key)
+		s.auditAction(ctx, &update.CallbackQuery.From, audit.ActionDelete, file.Key, err)
+		if err != nil {
+			logger.Error().Err(err).Str("file", file.Key).Msg("Unable to delete video")
+			editMessage(libraryErrorText("удалить видео", err), models.InlineKeyboardMarkup{})
+			return
+		}
+
+		logger.Info().Str("file", file.Key).Msg("Video deleted")
+		editMessage(fmt.Sprintf("🗑 Видео %s удалено", file.Key), models.InlineKeyboardMarkup{})
+	case LibraryEditCallback:
+		prompt, ok := libraryFieldPrompts[field]
+		if !ok {
+			logger.Warn().Msgf("Invalid callback data: %s", update.CallbackQuery.Data)
+			return
+		}
+
+		sent, err := b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID:      message.Chat.ID,
+			Text:        fmt.Sprintf("%s\n\n%s", file.Key, prompt),
+			ReplyMarkup: &models.ForceReply{ForceReply: true},
+		})
+		if err != nil {
+			logger.Warn().Err(err).Msg("Unable to ask for new value")
+			return
+		}
+
+		s.LibraryEdits.Put(sent.Chat.ID, update.CallbackQuery.From.ID, sent.ID, libraryEdit{key: file.Key, field: field})
+	}
+}
+
+// isLibraryEditReply ответ на запрос нового значения для видео
+func (s *streamBot) isLibraryEditReply(update *models.Update) bool {
+	message := update.Message
+	return message != nil && message.ReplyToMessage != nil && message.From != nil && len(message.Text) > 0 &&
+		s.LibraryEdits.Has(message.Chat.ID, message.From.ID, message.ReplyToMessage.ID)
+}
+
+func (s *streamBot) handleLibraryEditReply(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	chatID := update.Message.Chat.ID
+	edit, ok := s.LibraryEdits.Take(chatID, update.Message.From.ID, update.Message.ReplyToMessage.ID)
+	if !ok {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   "Запрос устарел, выберите видео в библиотеке заново",
+		})
+		return
+	}
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Str("file", edit.key).
+		Str("field", string(edit.field)).
+		Msg("Handle library edit")
+
+	value := strings.TrimSpace(update.Message.Text)
+	if value == "-" {
+		value = ""
+	}
+
+	key := edit.key
+	var err error
+	if edit.field == libraryFieldName {
+		key, err = s.Service.RenameVideo(ctx, edit.key, value)
+		s.auditAction(ctx, update.Message.From, audit.ActionRename, edit.key, err)
+	} else {
+		err = s.setLibraryMeta(ctx, edit, value)
+		s.auditAction(ctx, update.Message.From, audit.ActionEditMeta, edit.key, err)
+	}
+
+	if err != nil {
+		logger.Error().Err(err).Str("file", edit.key).Msg("Unable to change video")
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   libraryErrorText("изменить видео", err),
+		})
+		return
+	}
+
+	file, ok := s.Service.Video(key)
+	if !ok {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("✅ Видео сохранено как %s, но не попало в библиотеку", key),
+		})
+		return
+	}
+
+	text, keyboard := libraryFileCard(file, canManageLibrary(ctx))
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      chatID,
+		Text:        "✅ Изменения сохранены\n\n" + text,
+		ReplyMarkup: keyboard,
+	})
+}
+
+func (s *streamBot) setLibraryMeta(ctx context.Context, edit libraryEdit, value string) error {
+	file, ok := s.Service.Video(edit.key)
+	if !ok {
+		return fmt.Errorf("%w: %s", service.ErrUnknownVideo, edit.key)
+	}
+
+	title, category, tags := file.Meta.Title, file.Meta.Category, file.Meta.Tags
+	switch edit.field {
+	case libraryFieldTitle:
+		title = value
+	case libraryFieldCategory:
+		category = value
+	case libraryFieldTags:
+		tags = nil
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+
+	return s.Service.SetVideoMeta(ctx, edit.key, title, category, tags)
+}
+
+func libraryErrorText(action string, err error) string {
+	switch {
+	case errors.Is(err, storage.ErrReadOnly):
+		return fmt.Sprintf("❌ Не удалось %s: источник не поддерживает изменения", action)
+	case errors.Is(err, storage.ErrVideoExists):
+		return fmt.Sprintf("❌ Не удалось %s: видео с таким именем уже есть", action)
+	case errors.Is(err, service.ErrInvalidName):
+		return fmt.Sprintf("❌ Не удалось %s: недопустимое имя файла", action)
+	default:
+		return fmt.Sprintf("❌ Не удалось %s:\n%v", action, err)
+	}
+}
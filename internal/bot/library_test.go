@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestLibraryEdits() (*libraryEdits, *time.Time) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	edits := newLibraryEdits()
+	edits.now = func() time.Time { return now }
+
+	return edits, &now
+}
+
+func TestLibraryEditsOnlyEditorTakes(t *testing.T) {
+	edits, _ := newTestLibraryEdits()
+	edits.Put(100, 1, 10, libraryEdit{key: "a.ts", field: libraryFieldName})
+
+	// В общем чате на запрос может ответить другой пользователь
+	if edits.Has(100, 2, 10) {
+		t.Fatal("edit is found for another user")
+	}
+	if _, ok := edits.Take(100, 2, 10); ok {
+		t.Fatal("edit is taken by another user")
+	}
+	if _, ok := edits.Take(200, 1, 10); ok {
+		t.Fatal("edit is taken in another chat")
+	}
+
+	if !edits.Has(100, 1, 10) {
+		t.Fatal("edit is not found for editor")
+	}
+	edit, ok := edits.Take(100, 1, 10)
+	if !ok || edit.key != "a.ts" || edit.field != libraryFieldName {
+		t.Fatalf("Take() = %+v, %v, want edit of a.ts name", edit, ok)
+	}
+	if _, ok := edits.Take(100, 1, 10); ok {
+		t.Fatal("edit is taken twice")
+	}
+}
+
+func TestLibraryEditsExpire(t *testing.T) {
+	edits, now := newTestLibraryEdits()
+	edits.Put(100, 1, 10, libraryEdit{key: "a.ts", field: libraryFieldName})
+
+	*now = now.Add(libraryEditTTL + time.Second)
+	if _, ok := edits.Take(100, 1, 10); ok {
+		t.Fatal("expired edit is taken")
+	}
+
+	// Устаревшие запросы удаляются при добавлении новых
+	edits.Put(100, 1, 11, libraryEdit{key: "a.ts", field: libraryFieldName})
+	*now = now.Add(libraryEditTTL + time.Second)
+	edits.Put(100, 1, 12, libraryEdit{key: "b.ts", field: libraryFieldName})
+	if len(edits.items) != 1 {
+		t.Fatalf("items = %d, want 1", len(edits.items))
+	}
+}
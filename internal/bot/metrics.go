@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// knownCommands тексты сообщений, которые считаются отдельными командами в метриках
+var knownCommands = []string{
+	"/start",
+	UnlockVaultCommand,
+	ForgetKeysCommand,
+	AuditCommand,
+	string(ButtonTypeNext),
+	string(ButtonTypeReloadList),
+	string(ButtonTypeStatistics),
+	string(ButtonTypeStart),
+	string(ButtonTypeStop),
+	string(ButtonTypeQueue),
+	string(ButtonTypeLibrary),
+	QueueVideoMessagePrefix,
+	"stream_key:",
+}
+
+// knownCallbacks префиксы данных inline кнопок, которые считаются отдельными командами в метриках
+var knownCallbacks = []string{
+	NextVideoCallbackPrefix,
+	StartStreamCallbackPrefix,
+	StopStreamCallbackPrefix,
+	QueueCallbackPrefix,
+	// Действия библиотеки различаются по правам, поэтому проверяются раньше общего префикса
+	LibraryPageCallback,
+	LibraryFileCallback,
+	LibraryDeleteCallback,
+	LibraryApproveDeleteCallback,
+	LibraryEditCallback,
+	LibraryCallbackPrefix,
+	VaultSaveCallbackPrefix,
+	ForgetKeysCallbackPrefix,
+}
+
+func metricsMiddleware(next telegramBot.HandlerFunc) telegramBot.HandlerFunc {
+	return func(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+		monitoring.TelegramHandlers.WithLabelValues(updateCommand(update)).Inc()
+		next(ctx, b, update)
+	}
+}
+
+// updateCommand определяет команду для метрик, произвольный текст не попадает в метки, чтобы не утекли ключи и пароли
+func updateCommand(update *models.Update) string {
+	switch {
+	case update.Message != nil:
+		for _, command := range knownCommands {
+			if strings.HasPrefix(update.Message.Text, command) {
+				return command
+			}
+		}
+		return "message"
+	case update.CallbackQuery != nil:
+		for _, prefix := range knownCallbacks {
+			if strings.HasPrefix(update.CallbackQuery.Data, prefix) {
+				return "callback:" + prefix
+			}
+		}
+		return "callback"
+	case update.InlineQuery != nil:
+		return "inline"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/service"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/rs/zerolog"
+)
+
+// Notifier отправляет уведомления пользователям бота с подходящей ролью, пользователи должны заранее начать диалог с ботом
+type Notifier struct {
+	bot   *telegramBot.Bot
+	users map[int64]config.BotRole
+}
+
+func NewNotifier(b *telegramBot.Bot, users map[int64]config.BotRole) *Notifier {
+	return &Notifier{
+		bot:   b,
+		users: users,
+	}
+}
+
+// MissingKeys напоминает о незаданных ключах перед запуском трансляции по расписанию
+func (n *Notifier) MissingKeys(ctx context.Context, start time.Time, platforms []config.Platform) {
+	n.notify(ctx, config.BotRoleOwner, fmt.Sprintf(
+		"⏰ Трансляция по расписанию начнется в %s, но не добавлены ключи трансляций для платформ: %s",
+		start.Format("15:04 02.01.2006"),
+		service.JoinPlatforms(platforms),
+	))
+}
+
+// maxLibraryChangesListed сколько видео перечислять в уведомлении об изменении библиотеки, чтобы не превысить длину сообщения
+const maxLibraryChangesListed = 30
+
+// LibraryChanged сообщает о видео, которые появились или пропали в источниках
+func (n *Notifier) LibraryChanged(ctx context.Context, changes storage.Changes) {
+	var text strings.Builder
+	text.WriteString("📂 Библиотека видео обновлена")
+	writeKeysList(&text, "➕ Добавлены", changes.Added)
+	writeKeysList(&text, "➖ Удалены", changes.Removed)
+
+	n.notify(ctx, config.BotRoleOperator, text.String())
+}
+
+func writeKeysList(text *strings.Builder, title string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	fmt.Fprintf(text, "\n\n%s (%d):", title, len(keys))
+	for i, key := range keys {
+		if i == maxLibraryChangesListed {
+			fmt.Fprintf(text, "\n... и еще %d", len(keys)-i)
+			break
+		}
+
+		fmt.Fprintf(text, "\n%s", key)
+	}
+}
+
+func (n *Notifier) notify(ctx context.Context, role config.BotRole, text string) {
+	logger := zerolog.Ctx(ctx)
+
+	for user, userRole := range n.users {
+		if !userRole.Allows(role) {
+			continue
+		}
+
+		_, err := n.bot.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: user,
+			Text:   text,
+		})
+		if err != nil {
+			logger.Warn().Err(err).Int64("user", user).Msg("Unable to send notification")
+		}
+	}
+}
@@ -2,54 +2,48 @@ package bot
 
 import (
 	"context"
-	"slices"
 
 	telegramBot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog"
 )
 
+// startKeyboard расположение кнопок управления, пользователю показываются только доступные его роли
+var startKeyboard = [][]ButtonType{
+	{ButtonTypeNext, ButtonTypeQueue},
+	{ButtonTypeReloadList, ButtonTypeStatistics},
+	{ButtonTypeLibrary},
+	{ButtonTypeStart, ButtonTypeStop},
+}
+
 func (s *streamBot) handleStart(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
 	logger := zerolog.Ctx(ctx)
 
-	if slices.Contains(s.AcceptedUsers, update.Message.From.ID) {
-		logger.Info().
-			Int64("user", update.Message.From.ID).
-			Msgf("Handle start bot")
-
-		keyboard := models.ReplyKeyboardMarkup{
-			Keyboard: [][]models.KeyboardButton{
-				{
-					{
-						Text: string(ButtonTypeNext),
-					},
-					{
-						Text: string(ButtonTypeQueue),
-					},
-				},
-				{
-					{
-						Text: string(ButtonTypeReloadList),
-					},
-					{
-						Text: string(ButtonTypeStatistics),
-					},
-				},
-				{
-					{
-						Text: string(ButtonTypeStart),
-					},
-					{
-						Text: string(ButtonTypeStop),
-					},
-				},
-			},
+	role := contextRole(ctx)
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Str("role", string(role)).
+		Msgf("Handle start bot")
+
+	keyboard := models.ReplyKeyboardMarkup{
+		Keyboard: [][]models.KeyboardButton{},
+	}
+	for _, row := range startKeyboard {
+		buttons := []models.KeyboardButton{}
+		for _, button := range row {
+			if role.Allows(requiredRole(string(button))) {
+				buttons = append(buttons, models.KeyboardButton{Text: string(button)})
+			}
 		}
 
-		b.SendMessage(ctx, &telegramBot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Для управления потоком воспользуйтесь кнопками",
-			ReplyMarkup: keyboard,
-		})
+		if len(buttons) > 0 {
+			keyboard.Keyboard = append(keyboard.Keyboard, buttons)
+		}
 	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        "Для управления потоком воспользуйтесь кнопками",
+		ReplyMarkup: keyboard,
+	})
 }
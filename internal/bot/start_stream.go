@@ -2,10 +2,12 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"slices"
-	"strings"
 
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/player"
+	"github.com/Perkovec/StatiStream/internal/service"
 	telegramBot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog"
@@ -20,113 +22,87 @@ const (
 func (s *streamBot) preStartStream(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
 	logger := zerolog.Ctx(ctx)
 
-	if slices.Contains(s.AcceptedUsers, update.Message.From.ID) {
-		logger.Info().
-			Int64("user", update.Message.From.ID).
-			Msgf("Prehandle start stream")
-
-		alreadyStartedPlatforms := make([]string, 0, len(s.Streams))
-		for platform, stream := range s.Streams {
-			if stream.IsStarted() {
-				alreadyStartedPlatforms = append(alreadyStartedPlatforms, string(platform))
-			}
-		}
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Prehandle start stream")
 
-		if len(alreadyStartedPlatforms) > 0 {
-			b.SendMessage(ctx, &telegramBot.SendMessageParams{
-				ChatID: update.Message.Chat.ID,
-				Text:   fmt.Sprintf("На следующих платформах уже запущена трансляция: %s\nСначала остановите все трансляции", strings.Join(alreadyStartedPlatforms, ", ")),
-			})
-			return
-		}
-
-		missedTokenPlatforms := make([]string, 0, len(s.Streams))
-		for platform, stream := range s.Streams {
-			if !stream.HasToken() {
-				missedTokenPlatforms = append(missedTokenPlatforms, string(platform))
-			}
-		}
+	alreadyStartedPlatforms := s.Service.StartedPlatforms()
+	if len(alreadyStartedPlatforms) > 0 {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("На следующих платформах уже запущена трансляция: %s\nСначала остановите все трансляции", service.JoinPlatforms(alreadyStartedPlatforms)),
+		})
+		return
+	}
 
-		if len(missedTokenPlatforms) > 0 {
-			b.SendMessage(ctx, &telegramBot.SendMessageParams{
-				ChatID: update.Message.Chat.ID,
-				Text:   fmt.Sprintf("Не добавлены ключи трансляций для платформ: %s", strings.Join(missedTokenPlatforms, ", ")),
-			})
-			return
-		}
+	missedTokenPlatforms := s.Service.MissingKeyPlatforms()
+	if len(missedTokenPlatforms) > 0 {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   fmt.Sprintf("Не добавлены ключи трансляций для платформ: %s", service.JoinPlatforms(missedTokenPlatforms)),
+		})
+		return
+	}
 
-		keyboard := models.InlineKeyboardMarkup{
-			InlineKeyboard: [][]models.InlineKeyboardButton{
+	keyboard := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
 				{
-					{
-						Text:         "Подтвердить",
-						CallbackData: ApproveStartStreamCallback,
-					},
-					{
-						Text:         "Отмена",
-						CallbackData: CancelStartStreamCallback,
-					},
+					Text:         "Подтвердить",
+					CallbackData: ApproveStartStreamCallback,
+				},
+				{
+					Text:         "Отмена",
+					CallbackData: CancelStartStreamCallback,
 				},
 			},
-		}
-
-		b.SendMessage(ctx, &telegramBot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Вы точно уверены, что хотите запустить стрим?",
-			ReplyMarkup: keyboard,
-		})
+		},
 	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        "Вы точно уверены, что хотите запустить стрим?",
+		ReplyMarkup: keyboard,
+	})
 }
 
 func (s *streamBot) handleStartStream(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
 	logger := zerolog.Ctx(ctx)
 
-	isAccepted := slices.Contains(s.AcceptedUsers, update.CallbackQuery.From.ID)
-	cbAnswerParams := &telegramBot.AnswerCallbackQueryParams{
+	b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
 		CallbackQueryID: update.CallbackQuery.ID,
-		ShowAlert:       false,
-	}
-	if !isAccepted {
-		cbAnswerParams.ShowAlert = true
-		cbAnswerParams.Text = "Вы не можете управлять трансляцией"
-	}
+	})
 
-	b.AnswerCallbackQuery(ctx, cbAnswerParams)
-
-	if isAccepted {
-		if update.CallbackQuery.Message.Message != nil {
-			logger.Info().
-				Int64("user", update.CallbackQuery.From.ID).
-				Msgf("Handle start stream")
+	if update.CallbackQuery.Message.Message != nil {
+		logger.Info().
+			Int64("user", update.CallbackQuery.From.ID).
+			Msgf("Handle start stream")
 
-			var editText string
-			if update.CallbackQuery.Data == CancelStartStreamCallback {
-				editText = "Запуск стрима отменен"
-			}
+		var editText string
+		if update.CallbackQuery.Data == CancelStartStreamCallback {
+			editText = "Запуск стрима отменен"
+			s.audit(ctx, &update.CallbackQuery.From, audit.Entry{Action: audit.ActionStart, Result: audit.ResultCancelled})
+		}
 
-			if update.CallbackQuery.Data == ApproveStartStreamCallback {
-				video, contentLength, videoMeta := s.VideoStorage.GetNextVideo()
-				if video == nil {
-					editText = "Не удалось получить видео для запуска стрима"
-				} else {
-					err := s.Streams.Start()
-					if err != nil {
-						editText = fmt.Sprintf("Не удалось запустить стрим:\n%v", err)
-					} else {
-						editText = "Стрим запущен"
-						fmt.Printf("Start video \"%s\": %d\n", videoMeta.Filename, contentLength)
-						s.Streams.SetVideo(video, contentLength)
-					}
-				}
+		if update.CallbackQuery.Data == ApproveStartStreamCallback {
+			err := s.Service.Start(ctx)
+			s.auditAction(ctx, &update.CallbackQuery.From, audit.ActionStart, "", err)
+			switch {
+			case errors.Is(err, player.ErrNoVideo):
+				editText = "Не удалось получить видео для запуска стрима"
+			case err != nil:
+				editText = fmt.Sprintf("Не удалось запустить стрим:\n%v", err)
+			default:
+				editText = "Стрим запущен"
 			}
-			b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
-				ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
-				MessageID: update.CallbackQuery.Message.Message.ID,
-				Text:      editText,
-				ReplyMarkup: models.InlineKeyboardMarkup{
-					InlineKeyboard: [][]models.InlineKeyboardButton{},
-				},
-			})
 		}
+		b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+			MessageID: update.CallbackQuery.Message.Message.ID,
+			Text:      editText,
+			ReplyMarkup: models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{},
+			},
+		})
 	}
 }
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+func (s *streamBot) handleStatistics(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Handle statistics")
+
+	status := s.Service.Status()
+
+	var text strings.Builder
+	text.WriteString("📊 Статистика\n\n")
+	if status.Started {
+		text.WriteString("Трансляция запущена\n")
+	} else {
+		text.WriteString("Трансляция остановлена\n")
+	}
+
+	for _, platform := range status.Platforms {
+		state := "остановлена"
+		if platform.Started {
+			state = "идет трансляция"
+		}
+
+		key := "ключ не задан"
+		if platform.HasKey {
+			key = "ключ задан"
+		}
+
+		fmt.Fprintf(&text, "- %s: %s, %s\n", cases.Title(language.Russian, cases.Compact).String(string(platform.Platform)), state, key)
+	}
+
+	if video := status.CurrentVideo; video != nil {
+		title := video.Filename
+		if video.Title != "" {
+			title = fmt.Sprintf("%s (%s)", video.Title, video.Filename)
+		}
+
+		fmt.Fprintf(&text, "\nСейчас в эфире: %s, %.0f%%\n", title, video.Progress*100)
+	}
+
+	fmt.Fprintf(&text, "\nВидео в очереди: %d\n", len(status.Queue))
+	fmt.Fprintf(&text, "Видео в библиотеке: %d", len(s.Service.LibraryFiles()))
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   text.String(),
+	})
+}
@@ -2,8 +2,9 @@ package bot
 
 import (
 	"context"
-	"slices"
+	"fmt"
 
+	"github.com/Perkovec/StatiStream/internal/audit"
 	telegramBot "github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
 	"github.com/rs/zerolog"
@@ -18,30 +19,64 @@ const (
 func (s *streamBot) preStopStream(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
 	logger := zerolog.Ctx(ctx)
 
-	if slices.Contains(s.AcceptedUsers, update.Message.From.ID) {
-		logger.Info().
-			Int64("user", update.Message.From.ID).
-			Msgf("Prehandle stop stream")
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Msgf("Prehandle stop stream")
 
-		keyboard := models.InlineKeyboardMarkup{
-			InlineKeyboard: [][]models.InlineKeyboardButton{
+	keyboard := models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{
+					Text:         "Подтвердить",
+					CallbackData: ApproveStopStreamCallback,
+				},
 				{
-					{
-						Text:         "Подтвердить",
-						CallbackData: ApproveStopStreamCallback,
-					},
-					{
-						Text:         "Отмена",
-						CallbackData: CancelStopStreamCallback,
-					},
+					Text:         "Отмена",
+					CallbackData: CancelStopStreamCallback,
 				},
 			},
+		},
+	}
+
+	b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID:      update.Message.Chat.ID,
+		Text:        "Вы точно уверены, что хотите остановить стрим?",
+		ReplyMarkup: keyboard,
+	})
+}
+
+func (s *streamBot) handleStopStream(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	b.AnswerCallbackQuery(ctx, &telegramBot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+	})
+
+	if update.CallbackQuery.Message.Message != nil {
+		logger.Info().
+			Int64("user", update.CallbackQuery.From.ID).
+			Msgf("Handle stop stream")
+
+		editText := "Остановка стрима отменена"
+		if update.CallbackQuery.Data == ApproveStopStreamCallback {
+			err := s.Service.Stop(ctx)
+			s.auditAction(ctx, &update.CallbackQuery.From, audit.ActionStop, "", err)
+			if err != nil {
+				editText = fmt.Sprintf("Не удалось остановить стрим:\n%v", err)
+			} else {
+				editText = "Стрим остановлен"
+			}
+		} else {
+			s.audit(ctx, &update.CallbackQuery.From, audit.Entry{Action: audit.ActionStop, Result: audit.ResultCancelled})
 		}
 
-		b.SendMessage(ctx, &telegramBot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        "Вы точно уверены, что хотите остановить стрим?",
-			ReplyMarkup: keyboard,
+		b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
+			MessageID: update.CallbackQuery.Message.Message.ID,
+			Text:      editText,
+			ReplyMarkup: models.InlineKeyboardMarkup{
+				InlineKeyboard: [][]models.InlineKeyboardButton{},
+			},
 		})
 	}
 }
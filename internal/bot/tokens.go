@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gonanoid "github.com/matoous/go-nanoid/v2"
+)
+
+const (
+	pendingTokenTTL       = 5 * time.Minute
+	pendingTokensLimit    = 100
+	pendingTokensInterval = time.Minute
+)
+
+type pendingToken struct {
+	owner     int64
+	value     string
+	expiresAt time.Time
+}
+
+// pendingTokens временно хранит ключи трансляций под случайным идентификатором,
+// пока пользователь не подтвердит их. Записи живут ограниченное время, их число ограничено,
+// а у каждого пользователя хранится только последняя запись, чтобы не копить ключи при наборе inline запроса
+type pendingTokens struct {
+	mu sync.Mutex
+
+	ttl   time.Duration
+	limit int
+	now   func() time.Time
+	items map[string]pendingToken
+}
+
+func newPendingTokens(ttl time.Duration, limit int) *pendingTokens {
+	return &pendingTokens{
+		ttl:   ttl,
+		limit: limit,
+		now:   time.Now,
+		items: map[string]pendingToken{},
+	}
+}
+
+// Put сохраняет значение и возвращает его временный идентификатор, предыдущая запись пользователя удаляется
+func (p *pendingTokens) Put(owner int64, value string) (string, error) {
+	id, err := gonanoid.New()
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	p.removeExpired(now)
+
+	for key, item := range p.items {
+		if item.owner == owner {
+			delete(p.items, key)
+		}
+	}
+
+	if len(p.items) >= p.limit {
+		p.removeOldest()
+	}
+
+	p.items[id] = pendingToken{
+		owner:     owner,
+		value:     value,
+		expiresAt: now.Add(p.ttl),
+	}
+
+	return id, nil
+}
+
+// Take возвращает значение и удаляет запись, повторно получить значение нельзя. Запись может забрать только
+// пользователь, который ее создал, чтобы по идентификатору нельзя было применить чужой ключ
+func (p *pendingTokens) Take(id string, owner int64) (string, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	item, ok := p.items[id]
+	if !ok || item.owner != owner {
+		return "", false
+	}
+
+	delete(p.items, id)
+
+	if p.now().After(item.expiresAt) {
+		return "", false
+	}
+
+	return item.value, true
+}
+
+func (p *pendingTokens) Delete(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.items, id)
+}
+
+// Run периодически удаляет просроченные записи, пока не отменен контекст
+func (p *pendingTokens) Run(ctx context.Context) {
+	ticker := time.NewTicker(pendingTokensInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.removeExpired(p.now())
+			p.mu.Unlock()
+		}
+	}
+}
+
+func (p *pendingTokens) removeExpired(now time.Time) {
+	for key, item := range p.items {
+		if now.After(item.expiresAt) {
+			delete(p.items, key)
+		}
+	}
+}
+
+func (p *pendingTokens) removeOldest() {
+	var oldestKey string
+	var oldest time.Time
+	for key, item := range p.items {
+		if oldestKey == "" || item.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, item.expiresAt
+		}
+	}
+
+	delete(p.items, oldestKey)
+}
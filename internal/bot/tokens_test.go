@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestTokens(limit int) (*pendingTokens, *time.Time) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tokens := newPendingTokens(time.Minute, limit)
+	tokens.now = func() time.Time { return now }
+
+	return tokens, &now
+}
+
+func TestPendingTokensTakeOnce(t *testing.T) {
+	tokens, _ := newTestTokens(10)
+
+	id, err := tokens.Put(1, "key")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if value, ok := tokens.Take(id, 1); !ok || value != "key" {
+		t.Fatalf("Take() = %q, %v, want key", value, ok)
+	}
+	if _, ok := tokens.Take(id, 1); ok {
+		t.Fatal("token is taken twice")
+	}
+	if len(tokens.items) != 0 {
+		t.Fatalf("items = %d after take, want 0", len(tokens.items))
+	}
+}
+
+func TestPendingTokensOnlyCreatorTakes(t *testing.T) {
+	tokens, _ := newTestTokens(10)
+
+	id, err := tokens.Put(1, "key")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if _, ok := tokens.Take(id, 2); ok {
+		t.Fatal("token is taken by another user")
+	}
+	// Попытка другого пользователя не удаляет запись создателя
+	if value, ok := tokens.Take(id, 1); !ok || value != "key" {
+		t.Fatalf("Take() by creator = %q, %v, want key", value, ok)
+	}
+}
+
+func TestPendingTokensExpire(t *testing.T) {
+	tokens, now := newTestTokens(10)
+
+	id, err := tokens.Put(1, "key")
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	*now = now.Add(time.Minute + time.Second)
+	if _, ok := tokens.Take(id, 1); ok {
+		t.Fatal("expired token is taken")
+	}
+
+	// Просроченные записи удаляются при добавлении новых
+	if _, err := tokens.Put(2, "other"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	*now = now.Add(2 * time.Minute)
+	if _, err := tokens.Put(3, "third"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if len(tokens.items) != 1 {
+		t.Fatalf("items = %d, want only the last token", len(tokens.items))
+	}
+}
+
+func TestPendingTokensKeepLastPerUserAndLimit(t *testing.T) {
+	tokens, now := newTestTokens(2)
+
+	first, _ := tokens.Put(1, "first")
+	second, _ := tokens.Put(1, "second")
+	if _, ok := tokens.Take(first, 1); ok {
+		t.Fatal("previous token of user is kept")
+	}
+
+	*now = now.Add(time.Second)
+	tokens.Put(2, "user2")
+	*now = now.Add(time.Second)
+	tokens.Put(3, "user3")
+
+	// Лимит записей вытесняет самую старую
+	if _, ok := tokens.Take(second, 1); ok {
+		t.Fatal("oldest token is kept over limit")
+	}
+	if len(tokens.items) != 2 {
+		t.Fatalf("items = %d, want 2", len(tokens.items))
+	}
+}
@@ -0,0 +1,295 @@
+package bot
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/Perkovec/StatiStream/internal/transcode"
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxCallbackDataLength ограничение телеграмма на длину данных кнопки
+	maxCallbackDataLength = 64
+	// uploadTimeout сколько времени дается на скачивание, перекодирование и сохранение одного видео
+	uploadTimeout = time.Hour
+)
+
+// UploadParams загрузка видео в библиотеку через бота
+type UploadParams struct {
+	// MaxSize максимальный размер видео в байтах
+	MaxSize int64
+	// Directory папка или префикс ключа, в который сохраняются видео
+	Directory string
+	// Transcode пресет перекодирования, если не указан, то видео сохраняется как есть
+	Transcode  config.TranscodePreset
+	FfmpegPath string
+}
+
+// uploadedVideo видео или документ с видео из сообщения
+type uploadedVideo struct {
+	fileID string
+	name   string
+	size   int64
+}
+
+// isVideoUpload подходит ли сообщение для загрузки: видео или документ с видео, например .ts, который телеграмм не считает видео
+func isVideoUpload(update *models.Update) bool {
+	if update.Message == nil {
+		return false
+	}
+
+	if update.Message.Video != nil {
+		return true
+	}
+
+	document := update.Message.Document
+	return document != nil && (strings.HasPrefix(document.MimeType, "video/") ||
+		strings.EqualFold(filepath.Ext(document.FileName), transcode.Extension))
+}
+
+func videoFromMessage(message *models.Message) uploadedVideo {
+	if message.Video != nil {
+		return uploadedVideo{
+			fileID: message.Video.FileID,
+			name:   message.Video.FileName,
+			size:   message.Video.FileSize,
+		}
+	}
+
+	return uploadedVideo{
+		fileID: message.Document.FileID,
+		name:   message.Document.FileName,
+		size:   message.Document.FileSize,
+	}
+}
+
+func (s *streamBot) handleUpload(ctx context.Context, b *telegramBot.Bot, update *models.Update) {
+	logger := zerolog.Ctx(ctx)
+
+	video := videoFromMessage(update.Message)
+	logger.Info().
+		Int64("user", update.Message.From.ID).
+		Str("file", video.name).
+		Int64("size", video.size).
+		Msg("Handle video upload")
+
+	chatID := update.Message.Chat.ID
+	if video.size > s.Upload.MaxSize {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("❌ Видео слишком большое, можно загружать видео до %d МБ", s.Upload.MaxSize/1024/1024),
+		})
+		return
+	}
+
+	progress, err := b.SendMessage(ctx, &telegramBot.SendMessageParams{
+		ChatID: chatID,
+		Text:   "⏳ Скачиваю видео, это может занять время",
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Unable to send upload progress")
+	}
+
+	// Скачивание и перекодирование долгие, поэтому выполняются в фоне, чтобы бот продолжал отвечать на другие обновления
+	go s.runUpload(ctx, b, update.Message.From, chatID, progress, video)
+}
+
+// runUpload загружает видео в библиотеку, этапы загрузки показываются в сообщении progress, результат отправляется новым сообщением
+func (s *streamBot) runUpload(ctx context.Context, b *telegramBot.Bot, user *models.User, chatID int64, progress *models.Message, video uploadedVideo) {
+	ctx, cancel := context.WithTimeout(ctx, uploadTimeout)
+	defer cancel()
+
+	logger := zerolog.Ctx(ctx)
+
+	report := func(text string) {
+		if progress == nil {
+			return
+		}
+
+		_, err := b.EditMessageText(ctx, &telegramBot.EditMessageTextParams{
+			ChatID:    chatID,
+			MessageID: progress.ID,
+			Text:      text,
+		})
+		if err != nil {
+			logger.Warn().Err(err).Msg("Unable to update upload progress")
+		}
+	}
+
+	key, added, err := s.uploadVideo(ctx, b, video, report)
+	s.auditAction(ctx, user, audit.ActionUpload, cmp.Or(key, video.name), err)
+	if err != nil {
+		logger.Error().Err(err).Str("file", video.name).Msg("Unable to upload video")
+		report("❌ Загрузка прервана")
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   uploadErrorText(key, err),
+		})
+		return
+	}
+
+	report("✅ Загрузка завершена")
+
+	if !added {
+		b.SendMessage(ctx, &telegramBot.SendMessageParams{
+			ChatID: chatID,
+			Text:   fmt.Sprintf("⚠️ Видео сохранено как %s, но не попало в библиотеку, проверьте папку и расширения источника", key),
+		})
+		return
+	}
+
+	params := &telegramBot.SendMessageParams{
+		ChatID: chatID,
+		Text:   fmt.Sprintf("✅ Видео %s добавлено в библиотеку", key),
+	}
+
+	callbackData := fmt.Sprintf("%s:%s", SelectVideoQueueCallback, key)
+	if len(callbackData) <= maxCallbackDataLength {
+		params.ReplyMarkup = models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{
+						Text:         "Добавить в очередь",
+						CallbackData: callbackData,
+					},
+				},
+			},
+		}
+	}
+
+	b.SendMessage(ctx, params)
+}
+
+// uploadVideo скачивает видео из телеграмма, при необходимости перекодирует и сохраняет в хранилище,
+// о начале каждого этапа сообщается в report
+func (s *streamBot) uploadVideo(ctx context.Context, b *telegramBot.Bot, video uploadedVideo, report func(string)) (string, bool, error) {
+	name := uploadName(video.name)
+	if len(s.Upload.Transcode) > 0 {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + transcode.Extension
+	}
+	key := s.Upload.Directory + name
+
+	// Хранилище тоже не заменяет существующее видео, здесь проверяем заранее, чтобы не скачивать и не перекодировать зря
+	if slices.ContainsFunc(s.Service.Library(), func(file storage.FileInfo) bool { return file.Key == key }) {
+		return key, false, fmt.Errorf("streamBot.uploadVideo: %w: %s", storage.ErrVideoExists, key)
+	}
+
+	path, cleanup, err := s.downloadFile(ctx, b, video)
+	if err != nil {
+		return "", false, err
+	}
+	defer cleanup()
+
+	if len(s.Upload.Transcode) > 0 {
+		report("⏳ Перекодирую видео, это может занять время")
+
+		output := path + transcode.Extension
+		defer os.Remove(output)
+
+		err = transcode.Run(ctx, s.Upload.FfmpegPath, s.Upload.Transcode, path, output)
+		if err != nil {
+			return "", false, err
+		}
+
+		path = output
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("streamBot.uploadVideo.Open: %w", err)
+	}
+	defer f.Close()
+
+	report("⏳ Сохраняю видео в библиотеку")
+
+	added, err := s.Service.UploadVideo(ctx, key, f)
+
+	return key, added, err
+}
+
+// downloadFile скачивает файл во временную папку. Локальный сервер Bot API отдает путь к файлу на своем диске,
+// если бот запущен на той же машине, то файл читается оттуда напрямую
+func (s *streamBot) downloadFile(ctx context.Context, b *telegramBot.Bot, video uploadedVideo) (string, func(), error) {
+	file, err := b.GetFile(ctx, &telegramBot.GetFileParams{FileID: video.fileID})
+	if err != nil {
+		return "", nil, fmt.Errorf("streamBot.downloadFile.GetFile: %w", err)
+	}
+
+	if filepath.IsAbs(file.FilePath) {
+		if _, err := os.Stat(file.FilePath); err == nil {
+			return file.FilePath, func() {}, nil
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(file), nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("streamBot.downloadFile.NewRequest: %w", err)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("streamBot.downloadFile.Do: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("streamBot.downloadFile: unexpected status %s", res.Status)
+	}
+
+	tmp, err := os.CreateTemp("", "statistream-upload-*"+filepath.Ext(video.name))
+	if err != nil {
+		return "", nil, fmt.Errorf("streamBot.downloadFile.CreateTemp: %w", err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+
+	written, err := io.Copy(tmp, io.LimitReader(res.Body, s.Upload.MaxSize+1))
+	closeErr := tmp.Close()
+	switch {
+	case err != nil:
+		err = fmt.Errorf("streamBot.downloadFile.Copy: %w", err)
+	case closeErr != nil:
+		err = fmt.Errorf("streamBot.downloadFile.Close: %w", closeErr)
+	case written > s.Upload.MaxSize:
+		err = fmt.Errorf("streamBot.downloadFile: file is larger than %d bytes", s.Upload.MaxSize)
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return tmp.Name(), cleanup, nil
+}
+
+func uploadErrorText(key string, err error) string {
+	if errors.Is(err, storage.ErrVideoExists) {
+		return fmt.Sprintf("❌ Видео %s уже есть в библиотеке, переименуйте файл и отправьте его снова", key)
+	}
+
+	// Текст ошибки отправляется без разметки, в нем могут быть любые символы
+	return fmt.Sprintf("❌ Не удалось загрузить видео: %v", err)
+}
+
+// uploadName имя файла в хранилище, у видео, снятых на телефон, имени обычно нет, "." и ".." тоже не имена файлов
+func uploadName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" || name == "" {
+		return fmt.Sprintf("video_%s.mp4", time.Now().Format("20060102_150405"))
+	}
+
+	return name
+}
@@ -0,0 +1,178 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	telegramBot "github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/rs/zerolog"
+)
+
+const (
+	webhookSecretHeader    = "X-Telegram-Bot-Api-Secret-Token"
+	webhookShutdownTimeout = 5 * time.Second
+)
+
+// WebhookParams получение обновлений через webhook вместо long polling
+type WebhookParams struct {
+	// Listen адрес, на котором принимаются обновления
+	Listen string
+	// URL публичный адрес webhook, который регистрируется в Telegram, путь из него принимает обновления
+	URL string
+	// SecretToken Telegram передает его в заголовке каждого запроса, запросы без него отклоняются
+	SecretToken string
+	// CertFile и KeyFile необязательно, сервер слушает HTTPS с этим сертификатом
+	CertFile string
+	KeyFile  string
+	// SelfSigned передать сертификат в Telegram при регистрации webhook
+	SelfSigned bool
+}
+
+// WebhookServer принимает обновления от Telegram по HTTP и передает их обработчикам бота
+type WebhookServer struct {
+	bot    *telegramBot.Bot
+	params WebhookParams
+}
+
+func NewWebhookServer(b *telegramBot.Bot, params WebhookParams) *WebhookServer {
+	return &WebhookServer{
+		bot:    b,
+		params: params,
+	}
+}
+
+// Handler принимает обновления по пути из публичного адреса webhook
+func (s *WebhookServer) Handler() (http.Handler, error) {
+	webhookURL, err := url.Parse(s.params.URL)
+	if err != nil {
+		return nil, fmt.Errorf("WebhookServer.Handler.Parse: %w", err)
+	}
+
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	updates := s.bot.WebhookHandler()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+path, func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(webhookSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(s.params.SecretToken)) != 1 {
+			zerolog.Ctx(r.Context()).Warn().Str("remote", r.RemoteAddr).Msg("Webhook request with invalid secret token")
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		updates(w, r)
+	})
+
+	return mux, nil
+}
+
+// Run регистрирует webhook, принимает обновления до отмены контекста и удаляет webhook при остановке
+func (s *WebhookServer) Run(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	// Адрес занимается до регистрации webhook, чтобы первые обновления не приходили на закрытый порт
+	listener, err := net.Listen("tcp", s.params.Listen)
+	if err != nil {
+		return fmt.Errorf("WebhookServer.Run.Listen: %w", err)
+	}
+	defer listener.Close()
+
+	err = s.setWebhook(ctx)
+	if err != nil {
+		return err
+	}
+	defer s.deleteWebhook(ctx)
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(_ net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	}()
+
+	go s.bot.StartWebhook(ctx)
+
+	logger.Info().Msgf("Receiving bot updates with webhook on %s", s.params.Listen)
+
+	if len(s.params.CertFile) > 0 {
+		err = server.ServeTLS(listener, s.params.CertFile, s.params.KeyFile)
+	} else {
+		err = server.Serve(listener)
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("WebhookServer.Run.Serve: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WebhookServer) setWebhook(ctx context.Context) error {
+	params := &telegramBot.SetWebhookParams{
+		URL:         s.params.URL,
+		SecretToken: s.params.SecretToken,
+	}
+
+	if s.params.SelfSigned {
+		cert, err := os.Open(s.params.CertFile)
+		if err != nil {
+			return fmt.Errorf("WebhookServer.setWebhook.Open: %w", err)
+		}
+		defer cert.Close()
+
+		params.Certificate = &models.InputFileUpload{
+			Filename: filepath.Base(s.params.CertFile),
+			Data:     cert,
+		}
+	}
+
+	_, err := s.bot.SetWebhook(ctx, params)
+	if err != nil {
+		return fmt.Errorf("WebhookServer.setWebhook.SetWebhook: %w", err)
+	}
+
+	return nil
+}
+
+// deleteWebhook удаляет webhook, чтобы после остановки можно было снова получать обновления через long polling
+func (s *WebhookServer) deleteWebhook(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+
+	// Контекст сервиса к этому моменту уже отменен
+	deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), webhookShutdownTimeout)
+	defer cancel()
+
+	_, err := s.bot.DeleteWebhook(deleteCtx, &telegramBot.DeleteWebhookParams{})
+	if err != nil {
+		logger.Warn().Err(err).Msg("Unable to delete webhook")
+		return
+	}
+
+	logger.Info().Msg("Webhook deleted")
+}
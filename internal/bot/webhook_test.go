@@ -0,0 +1,185 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+const (
+	testBotToken      = "123456:test-token"
+	testWebhookSecret = "test-secret"
+	testBotUser       = 42
+)
+
+// fakeTelegramAPI заглушка Bot API, запоминает вызванные методы и параметры setWebhook
+type fakeTelegramAPI struct {
+	mu      sync.Mutex
+	methods []string
+	webhook map[string]string
+}
+
+func (api *fakeTelegramAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := path.Base(r.URL.Path)
+
+	api.mu.Lock()
+	api.methods = append(api.methods, method)
+	if method == "setWebhook" && r.ParseMultipartForm(1<<20) == nil {
+		api.webhook = map[string]string{
+			"url":          r.FormValue("url"),
+			"secret_token": r.FormValue("secret_token"),
+		}
+	}
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if method == "getMe" {
+		io.WriteString(w, `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"bot"}}`)
+		return
+	}
+
+	io.WriteString(w, `{"ok":true,"result":true}`)
+}
+
+func (api *fakeTelegramAPI) called(method string) bool {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return slices.Contains(api.methods, method)
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+func postUpdate(t *testing.T, url string, secret string, update string) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(update))
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(webhookSecretHeader, secret)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	res.Body.Close()
+
+	return res.StatusCode
+}
+
+func TestWebhookServer(t *testing.T) {
+	api := &fakeTelegramAPI{}
+	apiServer := httptest.NewServer(api)
+	defer apiServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b, err := NewBot(ctx, BotParams{
+		Users:     map[int64]config.BotRole{testBotUser: config.BotRoleOwner},
+		Token:     testBotToken,
+		APIServer: apiServer.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewBot: %v", err)
+	}
+
+	listen := freeAddress(t)
+	webhookURL := "http://" + listen + "/telegram"
+	server := NewWebhookServer(b, WebhookParams{
+		Listen:      listen,
+		URL:         webhookURL,
+		SecretToken: testWebhookSecret,
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Run(ctx)
+	}()
+
+	waitFor(t, "setWebhook", func() bool { return api.called("setWebhook") })
+
+	api.mu.Lock()
+	webhook := api.webhook
+	api.mu.Unlock()
+	if webhook["url"] != webhookURL || webhook["secret_token"] != testWebhookSecret {
+		t.Fatalf("setWebhook params = %v, want url %q and secret token", webhook, webhookURL)
+	}
+
+	update := `{"update_id":1,"message":{"message_id":1,"date":0,` +
+		`"chat":{"id":42,"type":"private"},"from":{"id":42,"is_bot":false,"first_name":"user"},"text":"/start"}}`
+
+	if status := postUpdate(t, webhookURL, "", update); status != http.StatusUnauthorized {
+		t.Fatalf("update without secret: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	if status := postUpdate(t, webhookURL, "wrong-secret", update); status != http.StatusUnauthorized {
+		t.Fatalf("update with wrong secret: status = %d, want %d", status, http.StatusUnauthorized)
+	}
+
+	if status := postUpdate(t, "http://"+listen+"/other", testWebhookSecret, update); status != http.StatusNotFound {
+		t.Fatalf("update on other path: status = %d, want %d", status, http.StatusNotFound)
+	}
+
+	if api.called("sendMessage") {
+		t.Fatal("rejected update reached the handler")
+	}
+
+	if status := postUpdate(t, webhookURL, testWebhookSecret, update); status != http.StatusOK {
+		t.Fatalf("valid update: status = %d, want %d", status, http.StatusOK)
+	}
+
+	// Обработчик /start отвечает клавиатурой через sendMessage
+	waitFor(t, "/start handler", func() bool { return api.called("sendMessage") })
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not stop after context cancel")
+	}
+
+	if !api.called("deleteWebhook") {
+		t.Fatal("deleteWebhook was not called on shutdown")
+	}
+}
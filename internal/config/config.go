@@ -3,11 +3,22 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/goccy/go-yaml"
 )
 
+const (
+	defaultStreamStaleAfter = 30 * time.Second
+	defaultRemindBefore     = 30 * time.Minute
+	defaultAuditLog         = "./logs/audit.jsonl"
+)
+
 type Platform string
 type PickStrategy string
 type SourceType string
@@ -17,28 +28,296 @@ const (
 )
 
 const (
-	PickStrategyRandom PickStrategy = "random"
+	PickStrategyRandom     PickStrategy = "random"
+	PickStrategySequential PickStrategy = "seq"
+)
+
+const (
+	SourceTypeDisk     SourceType = "disk"
+	SourceTypeS3       SourceType = "s3"
+	SourceTypePlaylist SourceType = "playlist"
+	SourceTypeHTTP     SourceType = "http"
+)
+
+type TranscodePreset string
+
+const (
+	TranscodePresetCPU   TranscodePreset = "cpu"
+	TranscodePresetNVENC TranscodePreset = "nvenc"
+)
+
+// BotRole роль пользователя бота, каждая следующая роль включает права предыдущей
+type BotRole string
+
+const (
+	// BotRoleViewer просмотр статуса и статистики
+	BotRoleViewer BotRole = "viewer"
+	// BotRoleOperator переключение видео, очередь, библиотека и перезагрузка данных
+	BotRoleOperator BotRole = "operator"
+	// BotRoleOwner ключи трансляции, запуск и остановка трансляции
+	BotRoleOwner BotRole = "owner"
+)
+
+// webhookSecretPattern допустимые Telegram символы секрета webhook
+var webhookSecretPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+var botRoleLevels = map[BotRole]int{
+	BotRoleViewer:   1,
+	BotRoleOperator: 2,
+	BotRoleOwner:    3,
+}
+
+// Allows проверяет, что у роли есть права роли required
+func (r BotRole) Allows(required BotRole) bool {
+	level, ok := botRoleLevels[r]
+	return ok && level >= botRoleLevels[required]
+}
+
+// maxBotAPIFileSize ограничение облачного Bot API на размер скачиваемых ботом файлов в мегабайтах
+const maxBotAPIFileSize = 20
+
+type LogFormat string
+type LogOutput string
+
+const (
+	LogFormatConsole LogFormat = "console"
+	LogFormatJSON    LogFormat = "json"
 )
 
 const (
-	SourceTypeDisk SourceType = "disk"
-	SourceTypeS3   SourceType = "s3"
+	LogOutputStdout LogOutput = "stdout"
+	LogOutputStderr LogOutput = "stderr"
+	LogOutputFile   LogOutput = "file"
 )
 
+// SourceNameSeparator отделяет имя источника от ключа видео в объединенной библиотеке
+const SourceNameSeparator = ":"
+
+// ConfigSource источник видеозаписей, имя и вес используются, только когда источников несколько
 type ConfigSource struct {
+	Name          string              `yaml:"name"`
+	Weight        int                 `yaml:"weight"`
 	Type          SourceType          `yaml:"type"`
 	DirectoryPath string              `yaml:"directory_path"`
 	Files         []string            `yaml:"files"`
+	Prefixes      []string            `yaml:"prefixes"`
+	Extensions    []string            `yaml:"extensions"`
+	Playlist      string              `yaml:"playlist"`
+	URL           string              `yaml:"url"`
 	PickStrategy  PickStrategy        `yaml:"pick_strategy"`
 	S3Bucket      string              `yaml:"s3bucket"`
 	S3Endpoint    string              `yaml:"s3endpoint"`
 	S3Credentials ConfigS3Credentials `yaml:"s3credentials"`
 	S3Region      string              `yaml:"s3region"`
+	// Watch следить за папкой на диске и добавлять новые видео автоматически
+	Watch bool `yaml:"watch"`
+	// WatchQuietPeriod сколько секунд файл не должен изменяться, чтобы считаться записанным
+	WatchQuietPeriod int `yaml:"watch_quiet_period"`
+	// RelistInterval через сколько минут перечитывать список файлов в s3, 0 чтобы не перечитывать
+	RelistInterval int `yaml:"relist_interval"`
+}
+
+// WatchQuietPeriodDuration сколько файл не должен изменяться, чтобы считаться записанным, 0 - по умолчанию
+func (c ConfigSource) WatchQuietPeriodDuration() time.Duration {
+	return time.Duration(c.WatchQuietPeriod) * time.Second
+}
+
+// RelistIntervalDuration интервал перечитывания списка файлов в s3
+func (c ConfigSource) RelistIntervalDuration() time.Duration {
+	return time.Duration(c.RelistInterval) * time.Minute
 }
 
 type ConfigBot struct {
-	Token         string  `yaml:"token"`
+	Token string `yaml:"token"`
+	// AcceptedUsers пользователи с ролью owner, оставлено для совместимости со старыми конфигурациями
 	AcceptedUsers []int64 `yaml:"accepted_users"`
+	// Users пользователи бота с ролями
+	Users []ConfigBotUser `yaml:"users"`
+	// Webhook необязательно, получение обновлений через webhook вместо long polling
+	Webhook ConfigWebhook `yaml:"webhook"`
+	// APIServer адрес локального сервера Bot API, он позволяет загружать через бота файлы больше 20 МБ
+	APIServer string       `yaml:"api_server"`
+	Upload    ConfigUpload `yaml:"upload"`
+}
+
+// ConfigWebhook адрес для приема обновлений от Telegram и публичный адрес, который регистрируется в Telegram
+type ConfigWebhook struct {
+	Listen string `yaml:"listen"`
+	// URL публичный HTTPS адрес webhook, например https://example.com/telegram
+	URL string `yaml:"url"`
+	// SecretToken значение заголовка X-Telegram-Bot-Api-Secret-Token, запросы без него отклоняются
+	SecretToken string `yaml:"secret_token"`
+	// CertFile и KeyFile необязательно, TLS сертификат, если HTTPS не завершается на прокси
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// SelfSigned передать самоподписанный сертификат в Telegram при регистрации webhook
+	SelfSigned bool `yaml:"self_signed"`
+}
+
+func (c ConfigWebhook) IsEnabled() bool {
+	return len(c.URL) > 0
+}
+
+type ConfigBotUser struct {
+	ID   int64   `yaml:"id"`
+	Role BotRole `yaml:"role"`
+}
+
+// ConfigUpload загрузка видео в библиотеку через телеграмм бота
+type ConfigUpload struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxSize максимальный размер видео в мегабайтах
+	MaxSize int64 `yaml:"max_size"`
+	// Directory папка или префикс ключа в хранилище, при нескольких источниках начинается с имени источника: clips:uploads/.
+	// Если в конце нет "/", то он добавляется при загрузке конфигурации
+	Directory string `yaml:"directory"`
+	// Transcode пресет перекодирования видео перед сохранением, если не указан, то видео сохраняется как есть
+	Transcode TranscodePreset `yaml:"transcode"`
+}
+
+// MaxSizeBytes максимальный размер загружаемого видео в байтах
+func (c ConfigUpload) MaxSizeBytes() int64 {
+	return c.MaxSize * 1024 * 1024
+}
+
+func (c ConfigBot) IsEnabled() bool {
+	return len(c.Token) > 0
+}
+
+// UserRoles роли всех пользователей бота, пользователи из accepted_users получают роль owner
+func (c ConfigBot) UserRoles() map[int64]BotRole {
+	roles := make(map[int64]BotRole, len(c.AcceptedUsers)+len(c.Users))
+	for _, user := range c.AcceptedUsers {
+		roles[user] = BotRoleOwner
+	}
+
+	for _, user := range c.Users {
+		roles[user.ID] = user.Role
+	}
+
+	return roles
+}
+
+// ConfigKeySource источник ключа трансляции: файл, переменная окружения или команда, которая выводит ключ в stdout
+type ConfigKeySource struct {
+	File    string `yaml:"file"`
+	Env     string `yaml:"env"`
+	Command string `yaml:"command"`
+}
+
+// ConfigKeyVault зашифрованное хранилище ключей трансляций
+type ConfigKeyVault struct {
+	Path          string `yaml:"path"`
+	PassphraseEnv string `yaml:"passphrase_env"`
+}
+
+func (c ConfigKeyVault) IsEnabled() bool {
+	return len(c.Path) > 0
+}
+
+// ConfigAPI локальный HTTP API для управления трансляцией и веб-интерфейс поверх него
+type ConfigAPI struct {
+	Listen            string `yaml:"listen"`
+	Token             string `yaml:"token"`
+	Dashboard         bool   `yaml:"dashboard"`
+	DashboardPassword string `yaml:"dashboard_password"`
+}
+
+func (c ConfigAPI) IsEnabled() bool {
+	return len(c.Listen) > 0
+}
+
+// ConfigMonitoring служебный HTTP сервер для систем мониторинга
+type ConfigMonitoring struct {
+	Listen           string `yaml:"listen"`
+	Metrics          bool   `yaml:"metrics"`
+	Health           bool   `yaml:"health"`
+	StreamStaleAfter int    `yaml:"stream_stale_after"`
+}
+
+func (c ConfigMonitoring) IsEnabled() bool {
+	return len(c.Listen) > 0
+}
+
+// StreamStaleTimeout время без записи видео в ffmpeg, после которого трансляция считается зависшей
+func (c ConfigMonitoring) StreamStaleTimeout() time.Duration {
+	if c.StreamStaleAfter <= 0 {
+		return defaultStreamStaleAfter
+	}
+
+	return time.Duration(c.StreamStaleAfter) * time.Second
+}
+
+// ConfigCache локальный кэш видео, следующее видео загружается в него заранее, пока играет текущее
+type ConfigCache struct {
+	Directory string `yaml:"directory"`
+	// MaxSize максимальный размер кэша в мегабайтах
+	MaxSize int64 `yaml:"max_size"`
+}
+
+func (c ConfigCache) IsEnabled() bool {
+	return len(c.Directory) > 0
+}
+
+// MaxSizeBytes максимальный размер кэша в байтах
+func (c ConfigCache) MaxSizeBytes() int64 {
+	return c.MaxSize * 1024 * 1024
+}
+
+// ConfigLog настройки журнала, по умолчанию журнал пишется в консоль и в файл с ежедневной ротацией
+type ConfigLog struct {
+	Level   string        `yaml:"level"`
+	Format  LogFormat     `yaml:"format"`
+	Outputs []LogOutput   `yaml:"outputs"`
+	File    ConfigLogFile `yaml:"file"`
+}
+
+// ConfigLogFile файл журнала, он всегда пишется в JSON
+type ConfigLogFile struct {
+	Path        string `yaml:"path"`
+	MaxSize     int    `yaml:"max_size"`
+	MaxAge      int    `yaml:"max_age"`
+	MaxBackups  int    `yaml:"max_backups"`
+	RotateEvery string `yaml:"rotate_every"`
+	Compress    bool   `yaml:"compress"`
+}
+
+// ConfigSchedule сетка вещания: в каждый слот видео выбираются только из заданного тега, папки или плейлиста.
+// В on_air указывается, когда трансляция запускается и останавливается автоматически
+type ConfigSchedule struct {
+	Timezone     string                 `yaml:"timezone"`
+	Slots        []ConfigScheduleSlot   `yaml:"slots"`
+	OnAir        []ConfigScheduleWindow `yaml:"on_air"`
+	RemindBefore int                    `yaml:"remind_before"`
+}
+
+// RemindBeforeDuration за сколько до запуска по расписанию напоминать о незаданных ключах
+func (c ConfigSchedule) RemindBeforeDuration() time.Duration {
+	if c.RemindBefore <= 0 {
+		return defaultRemindBefore
+	}
+
+	return time.Duration(c.RemindBefore) * time.Minute
+}
+
+// ConfigScheduleWindow интервал времени по дням недели, если end меньше start, то интервал заканчивается на следующий день
+type ConfigScheduleWindow struct {
+	Days  []string `yaml:"days"`
+	Start string   `yaml:"start"`
+	End   string   `yaml:"end"`
+}
+
+// ConfigScheduleSlot слот сетки вещания, если end меньше start, то слот заканчивается на следующий день
+type ConfigScheduleSlot struct {
+	Name         string       `yaml:"name"`
+	Days         []string     `yaml:"days"`
+	Start        string       `yaml:"start"`
+	End          string       `yaml:"end"`
+	Tag          string       `yaml:"tag"`
+	Directory    string       `yaml:"directory"`
+	Playlist     []string     `yaml:"playlist"`
+	PickStrategy PickStrategy `yaml:"pick_strategy"`
+	HardCut      bool         `yaml:"hard_cut"`
 }
 
 type ConfigS3Credentials struct {
@@ -47,10 +326,35 @@ type ConfigS3Credentials struct {
 }
 
 type Config struct {
-	Platform   []Platform   `yaml:"platforms"`
-	Source     ConfigSource `yaml:"source"`
-	Bot        ConfigBot    `yaml:"bot"`
-	FfmpegPath string       `yaml:"ffmpeg_path"`
+	Platform      []Platform                   `yaml:"platforms"`
+	Source        ConfigSource                 `yaml:"source"`
+	Sources       []ConfigSource               `yaml:"sources"`
+	Bot           ConfigBot                    `yaml:"bot"`
+	FfmpegPath    string                       `yaml:"ffmpeg_path"`
+	StreamKeyFile map[Platform]string          `yaml:"stream_key_file"`
+	StreamKeys    map[Platform]ConfigKeySource `yaml:"stream_keys"`
+	KeyVault      ConfigKeyVault               `yaml:"key_vault"`
+	API           ConfigAPI                    `yaml:"api"`
+	Monitoring    ConfigMonitoring             `yaml:"monitoring"`
+	Log           ConfigLog                    `yaml:"log"`
+	Schedule      ConfigSchedule               `yaml:"schedule"`
+	Cache         ConfigCache                  `yaml:"cache"`
+	// AuditLog путь до JSONL файла журнала действий управления через бота, HTTP API и по расписанию, по умолчанию ./logs/audit.jsonl
+	AuditLog string `yaml:"audit_log"`
+}
+
+// KeySources объединяет stream_key_file и stream_keys, настройки из stream_keys приоритетнее
+func (c *Config) KeySources() map[Platform]ConfigKeySource {
+	sources := make(map[Platform]ConfigKeySource, len(c.StreamKeyFile)+len(c.StreamKeys))
+	for platform, path := range c.StreamKeyFile {
+		sources[platform] = ConfigKeySource{File: path}
+	}
+
+	for platform, source := range c.StreamKeys {
+		sources[platform] = source
+	}
+
+	return sources
 }
 
 func ParseConfigFromFile(path string) (*Config, error) {
@@ -65,6 +369,8 @@ func ParseConfigFromFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("ParseConfigFromFile.Unmarshal: %w", err)
 	}
 
+	applyDefaults(&config)
+
 	err = validateConfig(&config)
 	if err != nil {
 		return nil, fmt.Errorf("ParseConfigFromFile.validateConfig: %w", err)
@@ -73,35 +379,343 @@ func ParseConfigFromFile(path string) (*Config, error) {
 	return &config, nil
 }
 
+func applyDefaults(config *Config) {
+	if len(config.AuditLog) == 0 {
+		config.AuditLog = defaultAuditLog
+	}
+
+	if config.Bot.Upload.MaxSize == 0 {
+		config.Bot.Upload.MaxSize = maxBotAPIFileSize
+	}
+
+	// Ключ загруженного видео получается склеиванием папки и имени, поэтому папка должна заканчиваться на "/",
+	// кроме корня источника: clips:uploads -> clips:uploads/, clips: остается как есть
+	directory := config.Bot.Upload.Directory
+	if len(directory) > 0 && !strings.HasSuffix(directory, "/") && !strings.HasSuffix(directory, SourceNameSeparator) {
+		config.Bot.Upload.Directory = directory + "/"
+	}
+
+	config.Source.applyDefaults()
+	for i := range config.Sources {
+		config.Sources[i].applyDefaults()
+	}
+	for i := range config.Schedule.Slots {
+		config.Schedule.Slots[i].applyDefaults()
+	}
+}
+
+func (c *ConfigSource) applyDefaults() {
+	// Плейлист по умолчанию воспроизводится по порядку, остальные источники как раньше выбирают видео случайно
+	if len(c.PickStrategy) == 0 {
+		c.PickStrategy = PickStrategyRandom
+		if c.Type == SourceTypePlaylist {
+			c.PickStrategy = PickStrategySequential
+		}
+	}
+
+	if c.Weight == 0 {
+		c.Weight = 1
+	}
+}
+
+func (c *ConfigScheduleSlot) applyDefaults() {
+	// Плейлист слота по умолчанию воспроизводится по порядку, как и плейлист-источник
+	if len(c.PickStrategy) == 0 {
+		c.PickStrategy = PickStrategyRandom
+		if len(c.Playlist) > 0 {
+			c.PickStrategy = PickStrategySequential
+		}
+	}
+}
+
 func validateConfig(config *Config) error {
 	// Проверяем что указаны платформы для стриминга
 	if len(config.Platform) == 0 {
 		return errors.New("empty 'platforms' list")
 	}
 
+	// Указывается либо один источник видео в source, либо несколько в sources
+	if len(config.Sources) > 0 {
+		if len(config.Source.Type) > 0 {
+			return errors.New("only one of 'source' and 'sources' can be specified")
+		}
+
+		if err := validateSources(config.Sources); err != nil {
+			return err
+		}
+	} else if err := validateSource(config.Source); err != nil {
+		return err
+	}
+
+	// Проверяем что источники ключей указаны для известных платформ и у каждого задан ровно один способ получения
+	for platform, source := range config.KeySources() {
+		if !slices.Contains(config.Platform, platform) {
+			return fmt.Errorf("stream key source for platform '%s' which is not in 'platforms'", platform)
+		}
+
+		specified := 0
+		for _, value := range []string{source.File, source.Env, source.Command} {
+			if len(value) > 0 {
+				specified++
+			}
+		}
+
+		if specified != 1 {
+			return fmt.Errorf("stream key source for platform '%s' must have exactly one of file, env or command", platform)
+		}
+	}
+
+	// Бот не обязателен, но если он указан, то для него должны быть указаны одобренные пользователи
+	if config.Bot.IsEnabled() && len(config.Bot.AcceptedUsers) == 0 && len(config.Bot.Users) == 0 {
+		return errors.New("list of accepted users for telegram bot is empty")
+	}
+
+	if err := validateBotUsers(config.Bot); err != nil {
+		return err
+	}
+
+	if config.Bot.Webhook.IsEnabled() {
+		if err := validateWebhook(config.Bot.Webhook); err != nil {
+			return err
+		}
+	}
+
+	// Для HTTP API обязательно указывать токен доступа
+	if config.API.IsEnabled() && len(config.API.Token) == 0 {
+		return errors.New("http api token not specified")
+	}
+
+	if config.Bot.Upload.Enabled {
+		if err := validateUpload(config); err != nil {
+			return err
+		}
+	}
+
+	if config.Cache.IsEnabled() && config.Cache.MaxSize <= 0 {
+		return errors.New("cache max_size must be positive")
+	}
+
+	if err := validateSchedule(config.Schedule); err != nil {
+		return err
+	}
+
+	return validateLog(config.Log)
+}
+
+func validateSource(source ConfigSource) error {
 	// Проверяем что указан источник видео
-	if !isValidSourceType(config.Source.Type) {
-		return fmt.Errorf("invalid source type: %s", config.Source.Type)
+	if !isValidSourceType(source.Type) {
+		return fmt.Errorf("invalid source type: %s", source.Type)
 	}
 
-	// Проверяем что указана папка с видео или список файлов
-	if len(config.Source.DirectoryPath) == 0 && len(config.Source.Files) == 0 {
-		return fmt.Errorf("not specified source directory path or files list")
+	// Проверяем что указан плейлист, адрес, папка с видео или список файлов
+	switch {
+	case source.Type == SourceTypePlaylist:
+		if len(source.Playlist) == 0 {
+			return errors.New("not specified source playlist")
+		}
+	case source.Type == SourceTypeHTTP:
+		if len(source.URL) == 0 {
+			return errors.New("not specified source url")
+		}
+	case len(source.DirectoryPath) == 0 && len(source.Files) == 0 && len(source.Prefixes) == 0:
+		return fmt.Errorf("not specified source directory path, prefixes or files list")
 	}
 
-	// Проверяем что есть ключ бота
-	if len(config.Bot.Token) == 0 {
-		return errors.New("telegram bot token not specified")
+	for _, extension := range source.Extensions {
+		if !strings.HasPrefix(extension, ".") {
+			return fmt.Errorf("source extension must start with a dot: %s", extension)
+		}
 	}
 
-	// Проверяем что для бота указаны одобренные пользователи
-	if len(config.Bot.AcceptedUsers) == 0 {
-		return errors.New("list of accepted users for telegram bot is empty")
+	if !isValidPickStrategy(source.PickStrategy) {
+		return fmt.Errorf("invalid pick strategy: %s", source.PickStrategy)
+	}
+
+	if source.Watch && source.Type != SourceTypeDisk {
+		return fmt.Errorf("watch is supported only for disk source, not %s", source.Type)
+	}
+
+	if source.RelistInterval < 0 || source.WatchQuietPeriod < 0 {
+		return errors.New("source relist_interval and watch_quiet_period must not be negative")
+	}
+
+	if source.RelistInterval > 0 && source.Type != SourceTypeS3 {
+		return fmt.Errorf("relist_interval is supported only for s3 source, not %s", source.Type)
+	}
+
+	if source.Weight < 0 {
+		return fmt.Errorf("negative source weight: %d", source.Weight)
 	}
 
 	return nil
 }
 
+// validateSources проверяет список источников, имя источника становится префиксом ключей его видео
+func validateSources(sources []ConfigSource) error {
+	names := map[string]bool{}
+	for _, source := range sources {
+		if len(source.Name) == 0 {
+			return errors.New("not specified name for one of 'sources'")
+		}
+
+		if strings.ContainsAny(source.Name, SourceNameSeparator+"/") {
+			return fmt.Errorf("source name '%s' must not contain '%s' or '/'", source.Name, SourceNameSeparator)
+		}
+
+		if names[source.Name] {
+			return fmt.Errorf("duplicate source name: %s", source.Name)
+		}
+		names[source.Name] = true
+
+		if err := validateSource(source); err != nil {
+			return fmt.Errorf("source '%s': %w", source.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func validateBotUsers(bot ConfigBot) error {
+	seen := make(map[int64]struct{}, len(bot.AcceptedUsers)+len(bot.Users))
+	for _, user := range bot.AcceptedUsers {
+		seen[user] = struct{}{}
+	}
+
+	for _, user := range bot.Users {
+		if user.ID == 0 {
+			return errors.New("telegram bot user id not specified")
+		}
+
+		if _, ok := botRoleLevels[user.Role]; !ok {
+			return fmt.Errorf("unknown role '%s' for telegram bot user %d", user.Role, user.ID)
+		}
+
+		if _, ok := seen[user.ID]; ok {
+			return fmt.Errorf("telegram bot user %d is specified more than once", user.ID)
+		}
+		seen[user.ID] = struct{}{}
+	}
+
+	return nil
+}
+
+func validateWebhook(webhook ConfigWebhook) error {
+	webhookURL, err := url.Parse(webhook.URL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return fmt.Errorf("bot webhook url must be an https url: %s", webhook.URL)
+	}
+
+	if len(webhook.Listen) == 0 {
+		return errors.New("bot webhook listen address not specified")
+	}
+
+	// Без секрета любой, кто знает адрес, мог бы отправить боту обновление от имени owner
+	if !webhookSecretPattern.MatchString(webhook.SecretToken) {
+		return errors.New("bot webhook secret_token must be 1-256 characters: A-Z, a-z, 0-9, _ and -")
+	}
+
+	if (len(webhook.CertFile) == 0) != (len(webhook.KeyFile) == 0) {
+		return errors.New("bot webhook cert_file and key_file must be specified together")
+	}
+
+	if webhook.SelfSigned && len(webhook.CertFile) == 0 {
+		return errors.New("bot webhook self_signed requires cert_file")
+	}
+
+	return nil
+}
+
+func validateUpload(config *Config) error {
+	upload := config.Bot.Upload
+
+	if upload.MaxSize < 0 {
+		return errors.New("bot upload max_size must be positive")
+	}
+
+	if upload.MaxSize > maxBotAPIFileSize && len(config.Bot.APIServer) == 0 {
+		return fmt.Errorf("bot upload max_size over %d MB requires local bot api_server", maxBotAPIFileSize)
+	}
+
+	if len(upload.Transcode) > 0 && upload.Transcode != TranscodePresetCPU && upload.Transcode != TranscodePresetNVENC {
+		return fmt.Errorf("invalid bot upload transcode preset: %s", upload.Transcode)
+	}
+
+	// При нескольких источниках папка должна указывать, в какой из них загружать видео
+	if len(config.Sources) > 0 {
+		name, _, ok := strings.Cut(upload.Directory, SourceNameSeparator)
+		if !ok || !slices.ContainsFunc(config.Sources, func(source ConfigSource) bool { return source.Name == name }) {
+			return fmt.Errorf("bot upload directory must start with source name and '%s': %s", SourceNameSeparator, upload.Directory)
+		}
+	}
+
+	return nil
+}
+
+func validateSchedule(schedule ConfigSchedule) error {
+	names := make([]string, 0, len(schedule.Slots))
+	for _, slot := range schedule.Slots {
+		if len(slot.Name) == 0 {
+			return errors.New("schedule slot name not specified")
+		}
+
+		if slices.Contains(names, slot.Name) {
+			return fmt.Errorf("duplicate schedule slot name: %s", slot.Name)
+		}
+		names = append(names, slot.Name)
+
+		specified := 0
+		if len(slot.Tag) > 0 {
+			specified++
+		}
+		if len(slot.Directory) > 0 {
+			specified++
+		}
+		if len(slot.Playlist) > 0 {
+			specified++
+		}
+
+		if specified != 1 {
+			return fmt.Errorf("schedule slot '%s' must have exactly one of tag, directory or playlist", slot.Name)
+		}
+
+		if !isValidPickStrategy(slot.PickStrategy) {
+			return fmt.Errorf("invalid pick strategy in schedule slot '%s': %s", slot.Name, slot.PickStrategy)
+		}
+	}
+
+	return nil
+}
+
+func validateLog(log ConfigLog) error {
+	if !slices.Contains([]string{"", "debug", "info", "warn", "error"}, log.Level) {
+		return fmt.Errorf("invalid log level: %s", log.Level)
+	}
+
+	if !slices.Contains([]LogFormat{"", LogFormatConsole, LogFormatJSON}, log.Format) {
+		return fmt.Errorf("invalid log format: %s", log.Format)
+	}
+
+	for _, output := range log.Outputs {
+		if !slices.Contains([]LogOutput{LogOutputStdout, LogOutputStderr, LogOutputFile}, output) {
+			return fmt.Errorf("invalid log output: %s", output)
+		}
+	}
+
+	if log.File.RotateEvery != "" {
+		every, err := time.ParseDuration(log.File.RotateEvery)
+		if err != nil || every < 0 {
+			return fmt.Errorf("invalid log rotate_every: %s", log.File.RotateEvery)
+		}
+	}
+
+	return nil
+}
+
+func isValidPickStrategy(strategy PickStrategy) bool {
+	return strategy == PickStrategyRandom || strategy == PickStrategySequential
+}
+
 func isValidSourceType(rawSource SourceType) bool {
-	return rawSource == SourceTypeDisk || rawSource == SourceTypeS3
+	return slices.Contains([]SourceType{SourceTypeDisk, SourceTypeS3, SourceTypePlaylist, SourceTypeHTTP}, rawSource)
 }
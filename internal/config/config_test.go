@@ -0,0 +1,28 @@
+package config
+
+import "testing"
+
+func TestApplyDefaultsUploadDirectory(t *testing.T) {
+	tests := []struct {
+		directory string
+		want      string
+	}{
+		{directory: "", want: ""},
+		{directory: "uploads", want: "uploads/"},
+		{directory: "uploads/", want: "uploads/"},
+		{directory: "clips:uploads", want: "clips:uploads/"},
+		// Корень источника уже отделен от имени видео
+		{directory: "clips:", want: "clips:"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.directory, func(t *testing.T) {
+			config := Config{Bot: ConfigBot{Upload: ConfigUpload{Directory: test.directory}}}
+			applyDefaults(&config)
+
+			if config.Bot.Upload.Directory != test.want {
+				t.Fatalf("upload directory = %q, want %q", config.Bot.Upload.Directory, test.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,9 @@ package helpers
 
 import (
 	"context"
-	"fmt"
 	"io"
+
+	"github.com/rs/zerolog"
 )
 
 type readerCtx struct {
@@ -15,26 +16,36 @@ type readerCtx struct {
 
 func (r *readerCtx) Read(p []byte) (n int, err error) {
 	if err := r.ctx.Err(); err != nil {
-		fmt.Printf("readerCtx.Ctx.Err: %v\n", err)
+		zerolog.Ctx(r.ctx).Debug().Err(err).Msg("Video reading canceled")
 		return 0, err
 	}
 	n, errR := r.r.Read(p)
 	if errR == io.EOF {
-		fmt.Println("readerCtx.Read: end of file")
-		r.eofCh <- struct{}{}
+		zerolog.Ctx(r.ctx).Debug().Msg("Video reached end of file")
+		r.next()
 
 		remainBytes := int(r.length % 188)
 		if remainBytes > 0 {
 			n = 188 - remainBytes
 			copy(p, make([]byte, n))
 		}
-	} else if errR != nil {
-		fmt.Printf("readerCtx.Read: %v\n", errR)
+	} else if errR != nil && r.ctx.Err() == nil {
+		// Видео дальше не прочитать (например, исчерпаны попытки переоткрыть его), переходим к следующему
+		zerolog.Ctx(r.ctx).Error().Err(errR).Msg("Unable to read video, switching to the next one")
+		r.next()
 	}
 
 	return n, errR
 }
 
+// next сообщает, что видео закончилось, если видео уже заменили другим, то сообщать некому
+func (r *readerCtx) next() {
+	select {
+	case r.eofCh <- struct{}{}:
+	case <-r.ctx.Done():
+	}
+}
+
 func NewReader(ctx context.Context, r io.Reader, contentLength int64, eofCh chan struct{}) io.Reader {
 	return &readerCtx{
 		ctx:    ctx,
@@ -0,0 +1,98 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/stream"
+	"github.com/rs/zerolog"
+)
+
+// Source описывает откуда можно получить ключ трансляции для платформы
+type Source struct {
+	File    string
+	Env     string
+	Command string
+}
+
+type Sources map[config.Platform]Source
+
+var ErrEmptyKey = errors.New("empty stream key")
+
+// EnvName возвращает имя переменной окружения по умолчанию для ключа платформы: STATISTREAM_TWITCH_KEY
+func EnvName(platform config.Platform) string {
+	return fmt.Sprintf("STATISTREAM_%s_KEY", strings.ToUpper(string(platform)))
+}
+
+// FromConfig возвращает источники ключей, указанные в конфигурации
+func FromConfig(cfg *config.Config) Sources {
+	sources := Sources{}
+	for platform, source := range cfg.KeySources() {
+		sources[platform] = Source{
+			File:    source.File,
+			Env:     source.Env,
+			Command: source.Command,
+		}
+	}
+
+	return sources
+}
+
+func (s Source) Load(ctx context.Context) (string, error) {
+	var key string
+	switch {
+	case s.Command != "":
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", s.Command)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("Source.Load.Command: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		key = string(out)
+	case s.File != "":
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("Source.Load.ReadFile: %w", err)
+		}
+		key = string(data)
+	case s.Env != "":
+		key = os.Getenv(s.Env)
+	}
+
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", ErrEmptyKey
+	}
+
+	return key, nil
+}
+
+// Apply загружает ключи из источников и устанавливает их в стримы соответствующих платформ.
+// Если ключ не удалось загрузить, платформа пропускается, и ключ можно будет указать через бота
+func Apply(ctx context.Context, streams stream.Streams, sources Sources) {
+	logger := zerolog.Ctx(ctx)
+
+	for platform, source := range sources {
+		platformStream, ok := streams[platform]
+		if !ok {
+			logger.Warn().Msgf("Stream key source for unknown platform: %s", platform)
+			continue
+		}
+
+		key, err := source.Load(ctx)
+		if err != nil {
+			logger.Warn().Err(err).Msgf("Stream key is not loaded for platform: %s", platform)
+			continue
+		}
+
+		platformStream.SetStreamToken(key)
+		logger.Info().Msgf("Stream key loaded for platform: %s", platform)
+	}
+}
@@ -0,0 +1,45 @@
+package keys
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/stream"
+	"github.com/rs/zerolog"
+)
+
+func TestApplySkipsKeysThatFailToLoad(t *testing.T) {
+	t.Setenv("STATISTREAM_TEST_KEY", " "+testStreamKey+"\n")
+	t.Setenv("STATISTREAM_TEST_EMPTY_KEY", "")
+
+	tests := []struct {
+		name    string
+		source  Source
+		wantKey bool
+	}{
+		{name: "env", source: Source{Env: "STATISTREAM_TEST_KEY"}, wantKey: true},
+		{name: "command", source: Source{Command: "echo " + testStreamKey}, wantKey: true},
+		{name: "empty env", source: Source{Env: "STATISTREAM_TEST_EMPTY_KEY"}},
+		{name: "missing file", source: Source{File: filepath.Join(t.TempDir(), "missing.txt")}},
+		{name: "failed command", source: Source{Command: "exit 1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			twitch := stream.NewTwitchStream(stream.TwitchStreamParams{Logger: zerolog.Nop()})
+			streams := stream.Streams{config.PlatformTwitch: twitch}
+
+			Apply(context.Background(), streams, Sources{
+				config.PlatformTwitch: test.source,
+				// Источник для платформы, которой нет в списке, тоже пропускается
+				config.Platform("unknown"): {Env: "STATISTREAM_TEST_KEY"},
+			})
+
+			if twitch.HasToken() != test.wantKey {
+				t.Fatalf("HasToken() = %v, want %v", twitch.HasToken(), test.wantKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package keys
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/stream"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	vaultVersion = 1
+	vaultKDF     = "argon2id"
+
+	argonTime    = 1
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+	argonKeyLen  = 32
+	saltLen      = 16
+)
+
+var (
+	ErrVaultLocked     = errors.New("key vault is locked")
+	ErrWrongPassphrase = errors.New("wrong key vault passphrase")
+	ErrDamagedVault    = errors.New("key vault file is damaged")
+)
+
+// vaultFile формат файла хранилища на диске, ключи трансляций хранятся только в зашифрованном виде
+type vaultFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Vault зашифрованное хранилище ключей трансляций: AES-GCM с ключом, полученным из пароля через argon2id
+type Vault struct {
+	mu   sync.Mutex
+	path string
+
+	salt []byte
+	aead cipher.AEAD
+	keys map[config.Platform]string
+}
+
+func NewVault(path string) *Vault {
+	return &Vault{
+		path: path,
+	}
+}
+
+func (v *Vault) IsUnlocked() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.aead != nil
+}
+
+// Unlock расшифровывает хранилище паролем, если файла хранилища еще нет, то он будет создан при первом сохранении
+func (v *Vault) Unlock(passphrase string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	data, err := os.ReadFile(v.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		salt := make([]byte, saltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("Vault.Unlock.Salt: %w", err)
+		}
+
+		aead, err := newVaultAEAD(passphrase, salt)
+		if err != nil {
+			return fmt.Errorf("Vault.Unlock: %w", err)
+		}
+
+		v.salt, v.aead, v.keys = salt, aead, map[config.Platform]string{}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Vault.Unlock.ReadFile: %w", err)
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("Vault.Unlock.Unmarshal: %w: %w", ErrDamagedVault, err)
+	}
+
+	if file.Version != vaultVersion || file.KDF != vaultKDF {
+		return fmt.Errorf("Vault.Unlock: unsupported vault version %d (%s)", file.Version, file.KDF)
+	}
+
+	aead, err := newVaultAEAD(passphrase, file.Salt)
+	if err != nil {
+		return fmt.Errorf("Vault.Unlock: %w", err)
+	}
+
+	// aead.Open паникует на nonce неверной длины
+	if len(file.Nonce) != aead.NonceSize() {
+		return fmt.Errorf("Vault.Unlock: %w: invalid nonce", ErrDamagedVault)
+	}
+
+	plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+
+	keys := map[config.Platform]string{}
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return fmt.Errorf("Vault.Unlock.Unmarshal: %w: %w", ErrDamagedVault, err)
+	}
+
+	v.salt, v.aead, v.keys = file.Salt, aead, keys
+	return nil
+}
+
+// Keys возвращает расшифрованные ключи трансляций
+func (v *Vault) Keys() (map[config.Platform]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.aead == nil {
+		return nil, ErrVaultLocked
+	}
+
+	keys := make(map[config.Platform]string, len(v.keys))
+	for platform, key := range v.keys {
+		keys[platform] = key
+	}
+
+	return keys, nil
+}
+
+// Save сохраняет ключ платформы и перезаписывает файл хранилища
+func (v *Vault) Save(platform config.Platform, key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.aead == nil {
+		return ErrVaultLocked
+	}
+
+	v.keys[platform] = key
+
+	return v.write()
+}
+
+// Forget удаляет файл хранилища и забывает ключи и пароль
+func (v *Vault) Forget() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.salt, v.aead, v.keys = nil, nil, nil
+
+	err := os.Remove(v.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Vault.Forget.Remove: %w", err)
+	}
+
+	return nil
+}
+
+func (v *Vault) write() error {
+	plain, err := json.Marshal(v.keys)
+	if err != nil {
+		return fmt.Errorf("Vault.write.Marshal: %w", err)
+	}
+
+	nonce := make([]byte, v.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("Vault.write.Nonce: %w", err)
+	}
+
+	data, err := json.Marshal(vaultFile{
+		Version: vaultVersion,
+		KDF:     vaultKDF,
+		Salt:    v.salt,
+		Nonce:   nonce,
+		Data:    v.aead.Seal(nil, nonce, plain, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("Vault.write.Marshal: %w", err)
+	}
+
+	// Пишем во временный файл и переименовываем, чтобы не повредить хранилище при сбое
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), filepath.Base(v.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("Vault.write.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Vault.write.Write: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("Vault.write.Close: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), v.path); err != nil {
+		return fmt.Errorf("Vault.write.Rename: %w", err)
+	}
+
+	return nil
+}
+
+func newVaultAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("newVaultAEAD.NewCipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("newVaultAEAD.NewGCM: %w", err)
+	}
+
+	return aead, nil
+}
+
+// ApplyVault устанавливает в стримы ключи из разблокированного хранилища и возвращает платформы, для которых ключ установлен
+func ApplyVault(streams stream.Streams, vault *Vault) ([]config.Platform, error) {
+	keys, err := vault.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make([]config.Platform, 0, len(keys))
+	for platform, key := range keys {
+		platformStream, ok := streams[platform]
+		if !ok {
+			continue
+		}
+
+		platformStream.SetStreamToken(key)
+		applied = append(applied, platform)
+	}
+
+	slices.Sort(applied)
+
+	return applied, nil
+}
@@ -0,0 +1,174 @@
+package keys
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+const (
+	testPassphrase = "correct horse battery staple"
+	testStreamKey  = "live_123456_secret"
+)
+
+// newSavedVault создает файл хранилища с одним ключом twitch
+func newSavedVault(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "keys.vault")
+	vault := NewVault(path)
+	if err := vault.Unlock(testPassphrase); err != nil {
+		t.Fatalf("Unlock new vault: %v", err)
+	}
+	if err := vault.Save(config.Platform("twitch"), testStreamKey); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	return path
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	path := newSavedVault(t)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Contains(string(data), testStreamKey) {
+		t.Fatal("vault file contains stream key in plain text")
+	}
+
+	vault := NewVault(path)
+	if _, err := vault.Keys(); !errors.Is(err, ErrVaultLocked) {
+		t.Fatalf("Keys() of locked vault error = %v, want %v", err, ErrVaultLocked)
+	}
+
+	if err := vault.Unlock(testPassphrase); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+
+	keys, err := vault.Keys()
+	if err != nil {
+		t.Fatalf("Keys: %v", err)
+	}
+	if keys["twitch"] != testStreamKey || len(keys) != 1 {
+		t.Fatalf("Keys() = %v, want twitch key", keys)
+	}
+}
+
+func TestVaultWrongPassphrase(t *testing.T) {
+	path := newSavedVault(t)
+
+	vault := NewVault(path)
+	err := vault.Unlock("wrong passphrase")
+	if !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("Unlock() error = %v, want %v", err, ErrWrongPassphrase)
+	}
+	if vault.IsUnlocked() {
+		t.Fatal("vault is unlocked with wrong passphrase")
+	}
+}
+
+func TestVaultDamagedFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		damage func(data []byte) []byte
+		want   error
+	}{
+		{
+			name:   "truncated",
+			damage: func(data []byte) []byte { return data[:len(data)/2] },
+			want:   ErrDamagedVault,
+		},
+		{
+			name: "short nonce",
+			damage: func(data []byte) []byte {
+				return modifyVaultFile(t, data, func(file *vaultFile) { file.Nonce = file.Nonce[:4] })
+			},
+			want: ErrDamagedVault,
+		},
+		{
+			// GCM не отличает измененные данные от неверного пароля
+			name: "changed data",
+			damage: func(data []byte) []byte {
+				return modifyVaultFile(t, data, func(file *vaultFile) { file.Data[0] ^= 0xff })
+			},
+			want: ErrWrongPassphrase,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := newSavedVault(t)
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if err := os.WriteFile(path, test.damage(data), 0o600); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+
+			err = NewVault(path).Unlock(testPassphrase)
+			if !errors.Is(err, test.want) {
+				t.Fatalf("Unlock() error = %v, want %v", err, test.want)
+			}
+		})
+	}
+}
+
+func TestVaultWriteLeavesNoTempFiles(t *testing.T) {
+	path := newSavedVault(t)
+
+	vault := NewVault(path)
+	if err := vault.Unlock(testPassphrase); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := vault.Save(config.Platform("youtube"), "other-key"); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(path) {
+		t.Fatalf("vault directory entries = %v, want only vault file", entries)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("vault file mode = %o, want 600", perm)
+	}
+
+	if err := vault.Forget(); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("vault file exists after Forget: %v", err)
+	}
+}
+
+func modifyVaultFile(t *testing.T, data []byte, modify func(file *vaultFile)) []byte {
+	t.Helper()
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	modify(&file)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	return data
+}
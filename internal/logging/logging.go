@@ -0,0 +1,160 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/rs/zerolog"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	defaultFilePath    = "./logs/statistream.log"
+	defaultMaxSize     = 100 // мегабайт
+	defaultRotateEvery = 24 * time.Hour
+)
+
+var defaultOutputs = []config.LogOutput{config.LogOutputStdout, config.LogOutputFile}
+
+// Bootstrap логгер для сообщений до чтения конфигурации
+func Bootstrap() zerolog.Logger {
+	return zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout}).With().Timestamp().Logger()
+}
+
+// New создает логгер по настройкам из конфигурации, возвращаемый io.Closer останавливает ротацию и закрывает файл журнала
+func New(cfg config.ConfigLog) (zerolog.Logger, io.Closer, error) {
+	level := zerolog.InfoLevel
+	if cfg.Level != "" {
+		parsed, err := zerolog.ParseLevel(cfg.Level)
+		if err != nil {
+			return zerolog.Logger{}, nil, fmt.Errorf("logging.New.ParseLevel: %w", err)
+		}
+		level = parsed
+	}
+
+	outputs := cfg.Outputs
+	if len(outputs) == 0 {
+		outputs = defaultOutputs
+	}
+
+	closer := &rotator{done: make(chan struct{})}
+	writers := make([]io.Writer, 0, len(outputs))
+	for _, output := range slices.Compact(slices.Clone(outputs)) {
+		switch output {
+		case config.LogOutputStdout:
+			writers = append(writers, formatWriter(cfg.Format, os.Stdout))
+		case config.LogOutputStderr:
+			writers = append(writers, formatWriter(cfg.Format, os.Stderr))
+		case config.LogOutputFile:
+			if closer.file != nil {
+				continue
+			}
+
+			file, err := newFile(cfg.File)
+			if err != nil {
+				return zerolog.Logger{}, nil, err
+			}
+			closer.file = file
+			writers = append(writers, file)
+		}
+	}
+
+	if closer.file != nil {
+		every := defaultRotateEvery
+		if cfg.File.RotateEvery != "" {
+			// Значение уже проверено при чтении конфигурации
+			every, _ = time.ParseDuration(cfg.File.RotateEvery)
+		}
+
+		if every > 0 {
+			go closer.run(every)
+		}
+	}
+
+	logger := zerolog.New(zerolog.MultiLevelWriter(writers...)).
+		Level(level).
+		With().
+		Timestamp().
+		Logger()
+
+	return logger, closer, nil
+}
+
+// WithComponent добавляет в логгер контекста поле component
+func WithComponent(ctx context.Context, component string) context.Context {
+	logger := zerolog.Ctx(ctx).With().Str("component", component).Logger()
+
+	return logger.WithContext(ctx)
+}
+
+func formatWriter(format config.LogFormat, out io.Writer) io.Writer {
+	if format == config.LogFormatJSON {
+		return out
+	}
+
+	return zerolog.ConsoleWriter{Out: out}
+}
+
+func newFile(cfg config.ConfigLogFile) (*lumberjack.Logger, error) {
+	path := cfg.Path
+	if path == "" {
+		path = defaultFilePath
+	}
+
+	maxSize := cfg.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultMaxSize
+	}
+
+	file := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxAge:     cfg.MaxAge,
+		MaxBackups: cfg.MaxBackups,
+		LocalTime:  true,
+		Compress:   cfg.Compress,
+	}
+
+	// Открываем файл сразу, чтобы ошибки доступа были видны при запуске, а не терялись при первой записи
+	if _, err := file.Write(nil); err != nil {
+		return nil, fmt.Errorf("logging.newFile: %w", err)
+	}
+
+	return file, nil
+}
+
+// rotator ротирует файл журнала по времени на границах интервала, отсчитанных от полуночи UTC,
+// ротацию по размеру делает сам lumberjack
+type rotator struct {
+	file *lumberjack.Logger
+	done chan struct{}
+}
+
+func (r *rotator) run(every time.Duration) {
+	for {
+		now := time.Now()
+		next := now.Truncate(every).Add(every)
+
+		select {
+		case <-r.done:
+			return
+		case <-time.After(next.Sub(now)):
+			r.file.Rotate()
+		}
+	}
+}
+
+func (r *rotator) Close() error {
+	close(r.done)
+
+	if r.file == nil {
+		return nil
+	}
+
+	return r.file.Close()
+}
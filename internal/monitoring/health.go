@@ -0,0 +1,165 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Check проверка состояния сервиса, nil означает что все в порядке
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// Health проверки для liveness и readiness проб систем оркестрации
+type Health struct {
+	mu        sync.Mutex
+	liveness  []namedCheck
+	readiness []namedCheck
+}
+
+func NewHealth() *Health {
+	return &Health{}
+}
+
+func (h *Health) AddLiveness(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.liveness = append(h.liveness, namedCheck{name: name, check: check})
+}
+
+func (h *Health) AddReadiness(name string, check Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.readiness = append(h.readiness, namedCheck{name: name, check: check})
+}
+
+func (h *Health) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	checks := slices.Clone(h.liveness)
+	h.mu.Unlock()
+
+	runChecks(w, r, checks)
+}
+
+func (h *Health) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	checks := slices.Clone(h.readiness)
+	h.mu.Unlock()
+
+	runChecks(w, r, checks)
+}
+
+// runChecks выполняет проверки параллельно, каждая ограничена по времени, чтобы зависшая проверка не вешала пробу
+func runChecks(w http.ResponseWriter, r *http.Request, checks []namedCheck) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	results := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			done := make(chan error, 1)
+			go func() { done <- check.check(ctx) }()
+
+			select {
+			case err := <-done:
+				results[i] = err
+			case <-ctx.Done():
+				results[i] = fmt.Errorf("check timed out: %w", ctx.Err())
+			}
+		}()
+	}
+	wg.Wait()
+
+	response := healthResponse{
+		Status: "ok",
+		Checks: make(map[string]string, len(checks)),
+	}
+	status := http.StatusOK
+	for i, check := range checks {
+		if results[i] != nil {
+			response.Status = "fail"
+			response.Checks[check.name] = results[i].Error()
+			status = http.StatusServiceUnavailable
+		} else {
+			response.Checks[check.name] = "ok"
+		}
+	}
+
+	writeJSON(w, status, response)
+}
+
+// Heartbeat отметка о том, что цикл продолжает работать
+type Heartbeat struct {
+	last atomic.Int64
+}
+
+func (h *Heartbeat) Beat() {
+	h.last.Store(time.Now().UnixNano())
+}
+
+// Check возвращает проверку, которая не проходит, если последняя отметка старше maxAge
+func (h *Heartbeat) Check(maxAge time.Duration) Check {
+	return func(_ context.Context) error {
+		last := h.last.Load()
+		if last == 0 {
+			return errors.New("loop has not started")
+		}
+
+		if age := time.Since(time.Unix(0, last)); age > maxAge {
+			return fmt.Errorf("no heartbeat for %s", age.Round(time.Second))
+		}
+
+		return nil
+	}
+}
+
+// BotStatus состояние получения обновлений телеграмм ботом
+type BotStatus struct {
+	polling   atomic.Bool
+	lastError atomic.Int64
+}
+
+func (b *BotStatus) SetPolling(polling bool) {
+	b.polling.Store(polling)
+}
+
+func (b *BotStatus) ReportError() {
+	b.lastError.Store(time.Now().UnixNano())
+}
+
+// Check возвращает проверку, которая не проходит, если бот не запущен или за последние errorWindow были ошибки
+func (b *BotStatus) Check(errorWindow time.Duration) Check {
+	return func(_ context.Context) error {
+		if !b.polling.Load() {
+			return errors.New("bot is not polling")
+		}
+
+		if last := b.lastError.Load(); last != 0 && time.Since(time.Unix(0, last)) < errorWindow {
+			return errors.New("bot had polling errors recently")
+		}
+
+		return nil
+	}
+}
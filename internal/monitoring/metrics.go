@@ -0,0 +1,88 @@
+package monitoring
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "statistream"
+
+var (
+	StreamUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_up",
+		Help:      "Whether the stream is running on the platform (1) or not (0).",
+	}, []string{"platform"})
+
+	FfmpegRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_restarts_total",
+		Help:      "Number of times ffmpeg was started again after a previous process for the platform.",
+	}, []string{"platform"})
+
+	FfmpegUnexpectedExits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_unexpected_exits_total",
+		Help:      "Number of times ffmpeg exited without the stream being stopped.",
+	}, []string{"platform"})
+
+	FfmpegInputBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_input_bytes_total",
+		Help:      "Bytes of video piped to ffmpeg.",
+	}, []string{"platform"})
+
+	FfmpegBitrate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_bitrate_kbits",
+		Help:      "Current output bitrate reported by ffmpeg, kbit/s.",
+	}, []string{"platform"})
+
+	FfmpegFPS = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_fps",
+		Help:      "Current frames per second reported by ffmpeg.",
+	}, []string{"platform"})
+
+	FfmpegSpeed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "ffmpeg_speed",
+		Help:      "Current processing speed reported by ffmpeg relative to real time.",
+	}, []string{"platform"})
+
+	VideoSwitches = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "video_switches_total",
+		Help:      "Number of times a new video was sent to the streams.",
+	})
+
+	StorageDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Latency of video storage operations.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+	}, []string{"operation"})
+
+	StorageErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_errors_total",
+		Help:      "Number of failed video storage operations.",
+	}, []string{"operation"})
+
+	TelegramHandlers = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_handler_calls_total",
+		Help:      "Number of Telegram updates handled per command.",
+	}, []string{"command"})
+)
+
+// RegisterQueueLength добавляет метрику длины очереди, значение берется из функции при каждом сборе метрик
+func RegisterQueueLength(queueLength func() int) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_length",
+		Help:      "Number of videos in the queue.",
+	}, func() float64 {
+		return float64(queueLength())
+	})
+}
@@ -0,0 +1,85 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Server отдает служебные эндпоинты для систем мониторинга, доступ к ним не требует авторизации
+type Server struct {
+	listen  string
+	metrics bool
+	health  *Health
+}
+
+type ServerParams struct {
+	Listen  string
+	Metrics bool
+	Health  *Health
+}
+
+func NewServer(params ServerParams) *Server {
+	return &Server{
+		listen:  params.Listen,
+		metrics: params.Metrics,
+		health:  params.Health,
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	if s.metrics {
+		mux.Handle("GET /metrics", promhttp.Handler())
+	}
+
+	if s.health != nil {
+		mux.HandleFunc("GET /healthz", s.health.handleLiveness)
+		mux.HandleFunc("GET /readyz", s.health.handleReadiness)
+	}
+
+	return mux
+}
+
+func (s *Server) Run(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	server := &http.Server{
+		Addr:              s.listen,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		server.Shutdown(shutdownCtx)
+	}()
+
+	logger.Info().Msgf("Starting monitoring server on %s", s.listen)
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("Server.Run.ListenAndServe: %w", err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
@@ -0,0 +1,51 @@
+package monitoring
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/storage"
+)
+
+const (
+	operationList = "list"
+	operationGet  = "get"
+)
+
+// instrumentedStorage считает время и ошибки получения видео и обновления списка файлов хранилища
+type instrumentedStorage struct {
+	storage.Storage
+}
+
+func InstrumentStorage(videoStorage storage.Storage) storage.Storage {
+	return &instrumentedStorage{Storage: videoStorage}
+}
+
+func (s *instrumentedStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *storage.VideoMeta, error) {
+	start := time.Now()
+	video, contentLength, videoMeta, err := s.Storage.GetNextVideo(ctx)
+	StorageDuration.WithLabelValues(operationGet).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		StorageErrors.WithLabelValues(operationGet).Inc()
+	}
+
+	return video, contentLength, videoMeta, err
+}
+
+func (s *instrumentedStorage) UpdateFilesList(ctx context.Context) error {
+	start := time.Now()
+	err := s.Storage.UpdateFilesList(ctx)
+	StorageDuration.WithLabelValues(operationList).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		StorageErrors.WithLabelValues(operationList).Inc()
+	}
+
+	return err
+}
+
+func (s *instrumentedStorage) Unwrap() storage.Storage {
+	return s.Storage
+}
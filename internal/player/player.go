@@ -0,0 +1,360 @@
+package player
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/Perkovec/StatiStream/internal/stream"
+	"github.com/rs/zerolog"
+)
+
+const (
+	heartbeatInterval = 5 * time.Second
+	slotCheckInterval = time.Second
+
+	nextVideoRetryInterval = 5 * time.Second
+)
+
+var (
+	ErrNoVideo    = storage.ErrNoVideo
+	ErrNotStarted = errors.New("stream is not started")
+)
+
+// Playback состояние текущего видео: сколько байт уже передано в трансляцию
+type Playback struct {
+	Meta      storage.VideoMeta
+	Size      int64
+	Position  int64
+	StartedAt time.Time
+}
+
+// countingReader считает байты, переданные из видео в трансляцию
+type countingReader struct {
+	io.ReadCloser
+	read atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.read.Add(int64(n))
+
+	return n, err
+}
+
+// videoPlayback контекст видео, которое передается в трансляцию. Одно видео может читаться несколькими платформами,
+// контекст отменяется, когда его больше не читает ни одна, так прерываются повторные попытки чтения из хранилища
+type videoPlayback struct {
+	cancel    context.CancelFunc
+	platforms int
+}
+
+// Player запускает трансляцию и подкладывает в нее следующие видео из хранилища
+type Player struct {
+	mu sync.Mutex
+
+	videoStorage storage.Storage
+	streams      stream.Streams
+	current      *storage.VideoMeta
+	size         int64
+	reader       *countingReader
+	playbacks    map[config.Platform]*videoPlayback
+	startedAt    time.Time
+	heartbeat    monitoring.Heartbeat
+	schedule     *schedule.Schedule
+}
+
+type PlayerParams struct {
+	VideoStorage storage.Storage
+	Streams      stream.Streams
+	// Schedule необязательно, нужно для прерывания видео при начале слота с hard_cut
+	Schedule *schedule.Schedule
+}
+
+func New(params PlayerParams) *Player {
+	return &Player{
+		videoStorage: params.VideoStorage,
+		streams:      params.Streams,
+		schedule:     params.Schedule,
+		playbacks:    map[config.Platform]*videoPlayback{},
+	}
+}
+
+// Start открывает первое видео и запускает трансляцию. Видео открывается без блокировки плеера,
+// чтобы медленное хранилище не задерживало статус и проверки готовности
+func (p *Player) Start(ctx context.Context) error {
+	playback, video, contentLength, videoMeta, err := p.openNextVideo(ctx)
+	if err != nil {
+		return fmt.Errorf("Player.Start: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err = p.streams.Start()
+	if err != nil {
+		closeVideo(playback, video)
+		return fmt.Errorf("Player.Start: %w", err)
+	}
+
+	p.setVideo(ctx, p.streams, playback, video, contentLength, videoMeta)
+
+	return nil
+}
+
+func (p *Player) Stop() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current = nil
+	err := p.streams.Stop()
+	for platform := range p.playbacks {
+		p.releasePlayback(platform)
+	}
+
+	return err
+}
+
+// Next прерывает текущее видео и запускает следующее
+func (p *Player) Next(ctx context.Context) error {
+	p.mu.Lock()
+	started := p.isStarted()
+	p.mu.Unlock()
+	if !started {
+		return ErrNotStarted
+	}
+
+	playback, video, contentLength, videoMeta, err := p.openNextVideo(ctx)
+	if err != nil {
+		return fmt.Errorf("Player.Next: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Пока открывалось видео, трансляцию могли остановить
+	if !p.isStarted() {
+		closeVideo(playback, video)
+		return ErrNotStarted
+	}
+
+	p.setVideo(ctx, p.streams, playback, video, contentLength, videoMeta)
+
+	return nil
+}
+
+// Current возвращает метаданные видео, которое сейчас транслируется
+func (p *Player) Current() *storage.VideoMeta {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current == nil {
+		return nil
+	}
+
+	current := *p.current
+	return &current
+}
+
+// Playback возвращает прогресс текущего видео или nil, если трансляция не идет
+func (p *Player) Playback() *Playback {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.current == nil {
+		return nil
+	}
+
+	return &Playback{
+		Meta:      *p.current,
+		Size:      p.size,
+		Position:  p.reader.read.Load(),
+		StartedAt: p.startedAt,
+	}
+}
+
+// Run ждет окончания текущего видео и запускает следующее, пока не отменен контекст
+func (p *Player) Run(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+
+	next := make(chan config.Platform)
+	for platform, platformStream := range p.streams {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-platformStream.NextVideo():
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case next <- platform:
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	// Начало слотов проверяем только если есть слоты, прерывающие текущее видео, иначе канал остается nil
+	var slotTick <-chan time.Time
+	if p.schedule.HasHardCuts() {
+		slotTicker := time.NewTicker(slotCheckInterval)
+		defer slotTicker.Stop()
+		slotTick = slotTicker.C
+	}
+	currentSlot := p.schedule.Current(time.Now())
+
+	p.heartbeat.Beat()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.heartbeat.Beat()
+		case now := <-slotTick:
+			slot, hardCut := p.schedule.SwitchSlot(currentSlot, now)
+			currentSlot = slot
+			if !hardCut {
+				continue
+			}
+
+			logger.Info().Msgf("Schedule slot \"%s\" started, switching video", slot.Name)
+			err := p.Next(ctx)
+			if err != nil && !errors.Is(err, ErrNotStarted) {
+				logger.Error().Err(err).Msg("Unable to switch video at schedule slot start")
+			}
+		case platform := <-next:
+			if !p.streams[platform].IsStarted() {
+				continue
+			}
+
+			playback, video, contentLength, videoMeta, err := p.openNextVideo(ctx)
+			if err != nil {
+				logger.Error().Err(err).Str("platform", string(platform)).Msgf("Unable to get next video, retry in %s", nextVideoRetryInterval)
+
+				// Без повтора трансляция осталась бы без видео до ручного переключения
+				go func() {
+					select {
+					case <-ctx.Done():
+					case <-time.After(nextVideoRetryInterval):
+						select {
+						case <-ctx.Done():
+						case next <- platform:
+						}
+					}
+				}()
+				continue
+			}
+
+			p.mu.Lock()
+			// Пока открывалось видео, трансляцию платформы могли остановить
+			if !p.streams[platform].IsStarted() {
+				p.mu.Unlock()
+				closeVideo(playback, video)
+				continue
+			}
+
+			p.setVideo(ctx, stream.Streams{platform: p.streams[platform]}, playback, video, contentLength, videoMeta)
+			p.mu.Unlock()
+			p.heartbeat.Beat()
+		}
+	}
+}
+
+// CheckAlive проверяет, что цикл Run не завис и блокировка плеера не удерживается бесконечно
+func (p *Player) CheckAlive(ctx context.Context) error {
+	if err := p.heartbeat.Check(3 * heartbeatInterval)(ctx); err != nil {
+		return fmt.Errorf("player loop: %w", err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		p.mu.Lock()
+		p.mu.Unlock()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		return errors.New("player lock is held too long")
+	}
+}
+
+// openNextVideo открывает следующее видео с отдельным контекстом воспроизведения. Контекст не зависит от ctx,
+// который может быть контекстом запроса к API, и отменяется только когда видео перестают читать
+func (p *Player) openNextVideo(ctx context.Context) (*videoPlayback, io.ReadCloser, int64, *storage.VideoMeta, error) {
+	videoCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	video, contentLength, videoMeta, err := p.videoStorage.GetNextVideo(videoCtx)
+	if err != nil {
+		cancel()
+		return nil, nil, 0, nil, err
+	}
+
+	return &videoPlayback{cancel: cancel}, video, contentLength, videoMeta, nil
+}
+
+// closeVideo закрывает открытое видео, которое так и не попало в трансляцию
+func closeVideo(playback *videoPlayback, video io.ReadCloser) {
+	video.Close()
+	playback.cancel()
+}
+
+func (p *Player) setVideo(ctx context.Context, streams stream.Streams, playback *videoPlayback, video io.ReadCloser, contentLength int64, videoMeta *storage.VideoMeta) {
+	logger := zerolog.Ctx(ctx)
+
+	logger.Info().Msgf("Start video \"%s\": %d", videoMeta.Filename, contentLength)
+
+	reader := &countingReader{ReadCloser: video}
+	streams.SetVideo(reader, contentLength)
+	monitoring.VideoSwitches.Inc()
+
+	for platform := range streams {
+		p.releasePlayback(platform)
+		p.playbacks[platform] = playback
+		playback.platforms++
+	}
+
+	p.current = videoMeta
+	p.size = contentLength
+	p.reader = reader
+	p.startedAt = time.Now()
+}
+
+// releasePlayback отвязывает видео от платформы и отменяет его контекст, если видео больше никто не читает
+func (p *Player) releasePlayback(platform config.Platform) {
+	playback, ok := p.playbacks[platform]
+	if !ok {
+		return
+	}
+	delete(p.playbacks, platform)
+
+	playback.platforms--
+	if playback.platforms == 0 {
+		playback.cancel()
+	}
+}
+
+func (p *Player) isStarted() bool {
+	for _, platformStream := range p.streams {
+		if platformStream.IsStarted() {
+			return true
+		}
+	}
+
+	return false
+}
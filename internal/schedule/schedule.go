@@ -0,0 +1,160 @@
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	_ "time/tzdata" // часовые пояса должны работать и в контейнерах без системной базы tzdata
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+// Slot слот сетки вещания и правило выбора видео для него
+type Slot struct {
+	Window
+
+	Name         string
+	Tag          string
+	Directory    string
+	Playlist     []string
+	PickStrategy config.PickStrategy
+	HardCut      bool
+}
+
+// Matches проверяет, подходит ли видео под слот
+func (s *Slot) Matches(key string, tags []string) bool {
+	switch {
+	case len(s.Tag) > 0:
+		return slices.Contains(tags, s.Tag)
+	case len(s.Directory) > 0:
+		return strings.HasPrefix(key, strings.TrimRight(s.Directory, "/")+"/")
+	default:
+		return slices.Contains(s.Playlist, key)
+	}
+}
+
+// Schedule сетка вещания, слоты проверяются по порядку и действует первый подходящий.
+// Кроме слотов в расписании задаются интервалы, когда трансляция должна идти
+type Schedule struct {
+	location     *time.Location
+	slots        []*Slot
+	onAir        []Window
+	remindBefore time.Duration
+}
+
+func New(cfg config.ConfigSchedule) (*Schedule, error) {
+	location := time.Local
+	if len(cfg.Timezone) > 0 {
+		var err error
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("schedule.New.LoadLocation: %w", err)
+		}
+	}
+
+	schedule := &Schedule{
+		location:     location,
+		remindBefore: cfg.RemindBeforeDuration(),
+	}
+	for _, slotCfg := range cfg.Slots {
+		window, err := ParseWindow(slotCfg.Days, slotCfg.Start, slotCfg.End)
+		if err != nil {
+			return nil, fmt.Errorf("schedule.New: slot '%s': %w", slotCfg.Name, err)
+		}
+
+		schedule.slots = append(schedule.slots, &Slot{
+			Window:       window,
+			Name:         slotCfg.Name,
+			Tag:          slotCfg.Tag,
+			Directory:    slotCfg.Directory,
+			Playlist:     slotCfg.Playlist,
+			PickStrategy: slotCfg.PickStrategy,
+			HardCut:      slotCfg.HardCut,
+		})
+	}
+
+	for _, windowCfg := range cfg.OnAir {
+		window, err := ParseWindow(windowCfg.Days, windowCfg.Start, windowCfg.End)
+		if err != nil {
+			return nil, fmt.Errorf("schedule.New: on_air: %w", err)
+		}
+
+		schedule.onAir = append(schedule.onAir, window)
+	}
+
+	return schedule, nil
+}
+
+// Current возвращает слот, который действует в момент now, или nil, если ни один слот не действует
+func (s *Schedule) Current(now time.Time) *Slot {
+	if s == nil {
+		return nil
+	}
+
+	now = now.In(s.location)
+	for _, slot := range s.slots {
+		if slot.Contains(now) {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// HasHardCuts проверяет, есть ли слоты, которые прерывают текущее видео при начале
+func (s *Schedule) HasHardCuts() bool {
+	if s == nil {
+		return false
+	}
+
+	return slices.ContainsFunc(s.slots, func(slot *Slot) bool { return slot.HardCut })
+}
+
+// SwitchSlot возвращает слот, действующий в момент now, и нужно ли прервать текущее видео:
+// это нужно, если с предыдущей проверки, когда действовал слот previous, начался слот с HardCut
+func (s *Schedule) SwitchSlot(previous *Slot, now time.Time) (*Slot, bool) {
+	slot := s.Current(now)
+
+	return slot, slot != nil && slot != previous && slot.HardCut
+}
+
+// HasOnAir проверяет, задано ли время автоматического запуска трансляции
+func (s *Schedule) HasOnAir() bool {
+	return s != nil && len(s.onAir) > 0
+}
+
+// OnAir проверяет, должна ли трансляция идти в момент now
+func (s *Schedule) OnAir(now time.Time) bool {
+	if s == nil {
+		return false
+	}
+
+	now = now.In(s.location)
+
+	return slices.ContainsFunc(s.onAir, func(window Window) bool { return window.Contains(now) })
+}
+
+// NextOnAir возвращает ближайшее время запуска трансляции по расписанию после now
+func (s *Schedule) NextOnAir(now time.Time) time.Time {
+	if s == nil {
+		return time.Time{}
+	}
+
+	now = now.In(s.location)
+
+	var next time.Time
+	for _, window := range s.onAir {
+		start := window.NextStart(now)
+		if !start.IsZero() && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+
+	return next
+}
+
+// RemindBefore за сколько до запуска по расписанию напоминать о незаданных ключах
+func (s *Schedule) RemindBefore() time.Duration {
+	return s.remindBefore
+}
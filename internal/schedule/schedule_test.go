@@ -0,0 +1,63 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+func TestSwitchSlot(t *testing.T) {
+	videoSchedule, err := New(config.ConfigSchedule{
+		Timezone: "UTC",
+		Slots: []config.ConfigScheduleSlot{
+			{Name: "news", Start: "10:00", End: "11:00", Tag: "news", HardCut: true},
+			{Name: "music", Start: "11:00", End: "12:00", Tag: "music"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2026, time.October, 19, hour, minute, 0, 0, time.UTC)
+	}
+
+	steps := []struct {
+		at       time.Time
+		wantSlot string
+		wantCut  bool
+	}{
+		{at: at(9, 59), wantSlot: "", wantCut: false},
+		// Начался слот с hard_cut
+		{at: at(10, 0), wantSlot: "news", wantCut: true},
+		// Слот продолжается, видео больше не прерывается
+		{at: at(10, 30), wantSlot: "news", wantCut: false},
+		// Слот без hard_cut дожидается конца текущего видео
+		{at: at(11, 0), wantSlot: "music", wantCut: false},
+		{at: at(12, 0), wantSlot: "", wantCut: false},
+	}
+
+	var current *Slot
+	for _, step := range steps {
+		slot, hardCut := videoSchedule.SwitchSlot(current, step.at)
+		current = slot
+
+		name := ""
+		if slot != nil {
+			name = slot.Name
+		}
+		if name != step.wantSlot || hardCut != step.wantCut {
+			t.Fatalf("SwitchSlot(%s) = %q, %v, want %q, %v", step.at.Format("15:04"), name, hardCut, step.wantSlot, step.wantCut)
+		}
+	}
+
+	if !videoSchedule.HasHardCuts() {
+		t.Fatal("HasHardCuts() = false, want true")
+	}
+
+	var empty *Schedule
+	if slot, hardCut := empty.SwitchSlot(nil, at(10, 0)); slot != nil || hardCut {
+		t.Fatalf("SwitchSlot() of nil schedule = %v, %v, want nil, false", slot, hardCut)
+	}
+}
@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window еженедельный интервал времени: дни недели и время начала и конца.
+// Если конец не позже начала, то интервал заканчивается на следующий день, а день недели считается по началу
+type Window struct {
+	days  []time.Weekday
+	start time.Duration
+	end   time.Duration
+}
+
+// ParseWindow разбирает дни недели (mon, tue, ...) и время в формате 15:04, пустой список дней означает каждый день
+func ParseWindow(days []string, start string, end string) (Window, error) {
+	window := Window{}
+	for _, day := range days {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return Window{}, fmt.Errorf("unknown weekday '%s'", day)
+		}
+
+		window.days = append(window.days, weekday)
+	}
+
+	var err error
+	window.start, err = parseTimeOfDay(start)
+	if err != nil {
+		return Window{}, err
+	}
+
+	window.end, err = parseTimeOfDay(end)
+	if err != nil {
+		return Window{}, err
+	}
+
+	return window, nil
+}
+
+// Contains проверяет, попадает ли момент в интервал, момент должен быть уже переведен в нужный часовой пояс
+func (w Window) Contains(t time.Time) bool {
+	timeOfDay := sinceMidnight(t)
+
+	if w.start < w.end {
+		return w.hasDay(t.Weekday()) && timeOfDay >= w.start && timeOfDay < w.end
+	}
+
+	// Интервал переходит через полночь: вечерняя часть относится к текущему дню, утренняя к предыдущему
+	if timeOfDay >= w.start {
+		return w.hasDay(t.Weekday())
+	}
+
+	return timeOfDay < w.end && w.hasDay(t.AddDate(0, 0, -1).Weekday())
+}
+
+// NextStart возвращает ближайшее начало интервала строго после момента t
+func (w Window) NextStart(t time.Time) time.Time {
+	// Начало задается временем на часах, а не сдвигом от полуночи, иначе в дни перехода на летнее время оно сместится на час
+	hour, minute := int(w.start/time.Hour), int(w.start%time.Hour/time.Minute)
+	for i := 0; i <= 7; i++ {
+		start := time.Date(t.Year(), t.Month(), t.Day()+i, hour, minute, 0, 0, t.Location())
+		if w.hasDay(start.Weekday()) && start.After(t) {
+			return start
+		}
+	}
+
+	return time.Time{}
+}
+
+func (w Window) hasDay(day time.Weekday) bool {
+	return len(w.days) == 0 || slices.Contains(w.days, day)
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', expected HH:MM", value)
+	}
+
+	return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
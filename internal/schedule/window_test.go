@@ -0,0 +1,229 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+func mustParseWindow(t *testing.T, days []string, start string, end string) Window {
+	t.Helper()
+
+	window, err := ParseWindow(days, start, end)
+	if err != nil {
+		t.Fatalf("ParseWindow: %v", err)
+	}
+
+	return window
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation: %v", err)
+	}
+
+	return location
+}
+
+func TestParseWindowErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		days  []string
+		start string
+		end   string
+	}{
+		{name: "unknown weekday", days: []string{"mon", "someday"}, start: "10:00", end: "12:00"},
+		{name: "invalid start", start: "25:00", end: "12:00"},
+		{name: "invalid end", start: "10:00", end: "noon"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseWindow(test.days, test.start, test.end); err == nil {
+				t.Fatal("ParseWindow() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 19 октября 2026 года понедельник
+	at := func(day int, clock string) time.Time {
+		parsed, err := time.Parse("15:04:05", clock)
+		if err != nil {
+			t.Fatalf("Parse: %v", err)
+		}
+
+		return time.Date(2026, time.October, day, parsed.Hour(), parsed.Minute(), parsed.Second(), 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		window Window
+		at     time.Time
+		want   bool
+	}{
+		{name: "start is inclusive", window: mustParseWindow(t, []string{"mon"}, "10:00", "12:00"), at: at(19, "10:00:00"), want: true},
+		{name: "inside", window: mustParseWindow(t, []string{"mon"}, "10:00", "12:00"), at: at(19, "11:59:59"), want: true},
+		{name: "end is exclusive", window: mustParseWindow(t, []string{"mon"}, "10:00", "12:00"), at: at(19, "12:00:00"), want: false},
+		{name: "before start", window: mustParseWindow(t, []string{"mon"}, "10:00", "12:00"), at: at(19, "09:59:59"), want: false},
+		{name: "other weekday", window: mustParseWindow(t, []string{"mon"}, "10:00", "12:00"), at: at(20, "11:00:00"), want: false},
+		{name: "weekday is case insensitive", window: mustParseWindow(t, []string{"Tue"}, "10:00", "12:00"), at: at(20, "11:00:00"), want: true},
+		{name: "no days means every day", window: mustParseWindow(t, nil, "10:00", "12:00"), at: at(25, "11:00:00"), want: true},
+
+		// Ночной интервал пятницы: вечер пятницы и утро субботы
+		{name: "midnight evening part", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(23, "23:00:00"), want: true},
+		{name: "midnight morning part counts for start day", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(24, "01:00:00"), want: true},
+		{name: "midnight end is exclusive", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(24, "02:00:00"), want: false},
+		{name: "midnight morning of start day", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(23, "01:00:00"), want: false},
+		{name: "midnight evening of next day", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(24, "23:00:00"), want: false},
+		{name: "midnight between end and start", window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"), at: at(23, "12:00:00"), want: false},
+
+		// Начало равно концу: интервал длится сутки
+		{name: "whole day", window: mustParseWindow(t, nil, "00:00", "00:00"), at: at(21, "13:37:00"), want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.window.Contains(test.at); got != test.want {
+				t.Fatalf("Contains(%s) = %v, want %v", test.at.Format(time.RFC3339), got, test.want)
+			}
+		})
+	}
+}
+
+func TestWindowNextStart(t *testing.T) {
+	berlin := mustLoadLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		name   string
+		window Window
+		at     time.Time
+		want   time.Time
+	}{
+		{
+			name:   "later the same day",
+			window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"),
+			at:     time.Date(2026, time.October, 23, 21, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 23, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "later in the week",
+			window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"),
+			at:     time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 23, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "strictly after the start",
+			window: mustParseWindow(t, []string{"fri"}, "22:00", "02:00"),
+			at:     time.Date(2026, time.October, 23, 22, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 30, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "next day without days",
+			window: mustParseWindow(t, nil, "08:00", "09:00"),
+			at:     time.Date(2026, time.October, 19, 8, 30, 0, 0, time.UTC),
+			want:   time.Date(2026, time.October, 20, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "next month",
+			window: mustParseWindow(t, []string{"mon"}, "10:00", "11:00"),
+			at:     time.Date(2026, time.October, 27, 0, 0, 0, 0, time.UTC),
+			want:   time.Date(2026, time.November, 2, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "keeps location",
+			window: mustParseWindow(t, []string{"mon"}, "10:00", "11:00"),
+			at:     time.Date(2026, time.October, 19, 9, 0, 0, 0, berlin),
+			want:   time.Date(2026, time.October, 19, 10, 0, 0, 0, berlin),
+		},
+		{
+			// 29 марта 2026 года в Берлине переход на летнее время
+			name:   "daylight saving time starts",
+			window: mustParseWindow(t, []string{"sun"}, "10:00", "11:00"),
+			at:     time.Date(2026, time.March, 29, 0, 30, 0, 0, berlin),
+			want:   time.Date(2026, time.March, 29, 10, 0, 0, 0, berlin),
+		},
+		{
+			// 25 октября 2026 года в Берлине переход на зимнее время
+			name:   "daylight saving time ends",
+			window: mustParseWindow(t, []string{"sun"}, "10:00", "11:00"),
+			at:     time.Date(2026, time.October, 25, 0, 30, 0, 0, berlin),
+			want:   time.Date(2026, time.October, 25, 10, 0, 0, 0, berlin),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := test.window.NextStart(test.at)
+			if !got.Equal(test.want) || got.Location() != test.want.Location() {
+				t.Fatalf("NextStart(%s) = %s, want %s", test.at, got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduleUsesTimezone(t *testing.T) {
+	videoSchedule, err := New(config.ConfigSchedule{
+		Timezone: "Asia/Tokyo",
+		Slots: []config.ConfigScheduleSlot{
+			{Name: "morning", Days: []string{"mon"}, Start: "09:00", End: "10:00", Tag: "news"},
+		},
+		OnAir: []config.ConfigScheduleWindow{
+			{Days: []string{"mon"}, Start: "09:00", End: "10:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	// В UTC это еще воскресенье, а в Токио уже понедельник 09:30
+	now := time.Date(2026, time.October, 19, 0, 30, 0, 0, time.UTC)
+	if slot := videoSchedule.Current(now); slot == nil || slot.Name != "morning" {
+		t.Fatalf("Current(%s) = %v, want morning slot", now, slot)
+	}
+	if !videoSchedule.OnAir(now) {
+		t.Fatalf("OnAir(%s) = false, want true", now)
+	}
+
+	// Понедельник 09:30 по UTC в Токио уже 18:30
+	later := now.Add(9 * time.Hour)
+	if slot := videoSchedule.Current(later); slot != nil {
+		t.Fatalf("Current(%s) = %s, want nil", later, slot.Name)
+	}
+
+	want := time.Date(2026, time.October, 26, 0, 0, 0, 0, time.UTC)
+	if next := videoSchedule.NextOnAir(later); !next.Equal(want) {
+		t.Fatalf("NextOnAir(%s) = %s, want %s", later, next, want)
+	}
+}
+
+func TestSlotMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		slot Slot
+		key  string
+		tags []string
+		want bool
+	}{
+		{name: "tag", slot: Slot{Tag: "news"}, key: "a.mp4", tags: []string{"music", "news"}, want: true},
+		{name: "other tag", slot: Slot{Tag: "news"}, key: "news.mp4", tags: []string{"music"}, want: false},
+		{name: "directory", slot: Slot{Directory: "morning"}, key: "morning/a.mp4", want: true},
+		{name: "directory with slash", slot: Slot{Directory: "morning/"}, key: "morning/a.mp4", want: true},
+		{name: "directory is not a name prefix", slot: Slot{Directory: "morning"}, key: "morning_show.mp4", want: false},
+		{name: "playlist", slot: Slot{Playlist: []string{"a.mp4", "b.mp4"}}, key: "b.mp4", want: true},
+		{name: "not in playlist", slot: Slot{Playlist: []string{"a.mp4"}}, key: "c.mp4", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.slot.Matches(test.key, test.tags); got != test.want {
+				t.Fatalf("Matches(%s, %v) = %v, want %v", test.key, test.tags, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/rs/zerolog"
+)
+
+// LibraryNotifier получает уведомления об изменениях библиотеки видео
+type LibraryNotifier interface {
+	LibraryChanged(ctx context.Context, changes storage.Changes)
+}
+
+// WatchLibrary начинает следить за изменениями в источниках видео, для которых это включено в конфигурации.
+// Возвращает false, если отслеживание не включено ни для одного источника
+func (s *Service) WatchLibrary(ctx context.Context, notifier LibraryNotifier) bool {
+	logger := zerolog.Ctx(ctx)
+
+	return storage.Watch(ctx, s.videoStorage, func(changes storage.Changes) {
+		logger.Info().
+			Strs("added", changes.Added).
+			Strs("removed", changes.Removed).
+			Msg("Library changed")
+
+		if notifier != nil {
+			notifier.LibraryChanged(ctx, changes)
+		}
+	})
+}
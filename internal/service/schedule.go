@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/rs/zerolog"
+)
+
+const scheduleCheckInterval = 5 * time.Second
+
+// ScheduleNotifier получает напоминания перед запуском трансляции по расписанию
+type ScheduleNotifier interface {
+	// MissingKeys вызывается, когда до запуска осталось меньше remind_before, а для части платформ не заданы ключи
+	MissingKeys(ctx context.Context, start time.Time, platforms []config.Platform)
+}
+
+// RunSchedule запускает и останавливает трансляцию по расписанию, пока не отменен контекст.
+// Трансляция запускается и останавливается только на границах интервалов, поэтому ручной запуск
+// или остановка внутри интервала не отменяются до следующей границы, а трансляцию, запущенную вручную
+// до начала интервала, расписание не останавливает. auditLog необязательно,
+// в него записываются запуски и остановки от имени расписания
+func (s *Service) RunSchedule(ctx context.Context, videoSchedule *schedule.Schedule, notifier ScheduleNotifier, auditLog *audit.Log) {
+	runner := &scheduleRunner{
+		schedule:    videoSchedule,
+		notifier:    notifier,
+		start:       s.Start,
+		stop:        s.Stop,
+		missingKeys: s.MissingKeyPlatforms,
+		record: func(ctx context.Context, action audit.Action, err error) {
+			recordSchedule(ctx, auditLog, action, err)
+		},
+		now: time.Now,
+	}
+
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		runner.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleRunner принимает решения расписания на каждой проверке
+type scheduleRunner struct {
+	schedule    *schedule.Schedule
+	notifier    ScheduleNotifier
+	start       func(ctx context.Context) error
+	stop        func(ctx context.Context) error
+	missingKeys func() []config.Platform
+	record      func(ctx context.Context, action audit.Action, err error)
+	now         func() time.Time
+
+	// onAir начало текущего интервала обработано, меняется только после успешного запуска или остановки,
+	// иначе попытка повторится на следующей проверке
+	onAir bool
+	// started трансляцию запустило само расписание, только ее оно и останавливает в конце интервала
+	started bool
+	// failing запуск или остановка по расписанию не удались, повторные ошибки в журнал аудита не пишутся
+	failing       bool
+	remindedStart time.Time
+}
+
+func (r *scheduleRunner) tick(ctx context.Context) {
+	logger := zerolog.Ctx(ctx)
+	now := r.now()
+
+	scheduled := r.schedule.OnAir(now)
+	switch {
+	case scheduled && !r.onAir:
+		logger.Info().Msg("Starting stream by schedule")
+		err := r.start(ctx)
+		switch {
+		case errors.Is(err, ErrAlreadyStarted):
+			// Уже запущенную вручную трансляцию расписание не запускало, в журнал она не попадает
+			logger.Info().Msg("Stream is already started, it will not be stopped by schedule")
+			r.onAir, r.failing = true, false
+		case err != nil:
+			logger.Error().Err(err).Msg("Unable to start stream by schedule")
+			if !r.failing {
+				r.record(ctx, audit.ActionStart, err)
+			}
+			r.failing = true
+		default:
+			r.record(ctx, audit.ActionStart, nil)
+			r.onAir, r.started, r.failing = true, true, false
+		}
+	case !scheduled && r.onAir && !r.started:
+		r.onAir = false
+	case !scheduled && r.onAir:
+		logger.Info().Msg("Stopping stream by schedule")
+		err := r.stop(ctx)
+		if err != nil {
+			logger.Error().Err(err).Msg("Unable to stop stream by schedule")
+			if !r.failing {
+				r.record(ctx, audit.ActionStop, err)
+			}
+			r.failing = true
+			break
+		}
+
+		r.record(ctx, audit.ActionStop, nil)
+		r.onAir, r.started, r.failing = false, false, false
+	default:
+		r.failing = false
+	}
+
+	// Напоминаем один раз для каждого запуска
+	next := r.schedule.NextOnAir(now)
+	if !next.IsZero() && next.Sub(now) <= r.schedule.RemindBefore() && !next.Equal(r.remindedStart) {
+		if missing := r.missingKeys(); len(missing) > 0 {
+			r.remindedStart = next
+			logger.Warn().Msgf("Scheduled start at %s, but stream keys are not set for platforms: %s", next.Format(time.DateTime), JoinPlatforms(missing))
+			if r.notifier != nil {
+				r.notifier.MissingKeys(ctx, next, missing)
+			}
+		}
+	}
+}
+
+func recordSchedule(ctx context.Context, auditLog *audit.Log, action audit.Action, err error) {
+	if auditLog == nil {
+		return
+	}
+
+	entry := audit.NewEntry(action, "", err)
+	entry.Username = audit.ActorSchedule
+
+	if err := auditLog.Record(entry); err != nil {
+		zerolog.Ctx(ctx).Error().Err(err).Str("action", string(action)).Msg("Unable to write audit log")
+	}
+}
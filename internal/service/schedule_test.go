@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/audit"
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+)
+
+var errTestStream = errors.New("ffmpeg is not available")
+
+// testScheduleRunner расписание с интервалом 10:00-11:00 каждый день по UTC и напоминанием за 30 минут
+type testScheduleRunner struct {
+	*scheduleRunner
+
+	clock     time.Time
+	startErrs []error
+	stopErrs  []error
+	starts    int
+	stops     int
+	missing   []config.Platform
+	records   []string
+	reminders []time.Time
+}
+
+func newTestScheduleRunner(t *testing.T) *testScheduleRunner {
+	t.Helper()
+
+	videoSchedule, err := schedule.New(config.ConfigSchedule{
+		Timezone:     "UTC",
+		OnAir:        []config.ConfigScheduleWindow{{Start: "10:00", End: "11:00"}},
+		RemindBefore: 30,
+	})
+	if err != nil {
+		t.Fatalf("schedule.New: %v", err)
+	}
+
+	r := &testScheduleRunner{}
+	r.scheduleRunner = &scheduleRunner{
+		schedule: videoSchedule,
+		notifier: r,
+		start: func(_ context.Context) error {
+			r.starts++
+			return popError(&r.startErrs)
+		},
+		stop: func(_ context.Context) error {
+			r.stops++
+			return popError(&r.stopErrs)
+		},
+		missingKeys: func() []config.Platform { return r.missing },
+		record: func(_ context.Context, action audit.Action, err error) {
+			r.records = append(r.records, fmt.Sprintf("%s:%v", action, err))
+		},
+		now: func() time.Time { return r.clock },
+	}
+
+	return r
+}
+
+func (r *testScheduleRunner) MissingKeys(_ context.Context, start time.Time, _ []config.Platform) {
+	r.reminders = append(r.reminders, start)
+}
+
+// tickAt проверяет расписание в момент hour:minute дня day октября 2026 года
+func (r *testScheduleRunner) tickAt(day int, hour int, minute int) {
+	r.clock = time.Date(2026, time.October, day, hour, minute, 0, 0, time.UTC)
+	r.tick(context.Background())
+}
+
+func popError(errs *[]error) error {
+	if len(*errs) == 0 {
+		return nil
+	}
+
+	err := (*errs)[0]
+	*errs = (*errs)[1:]
+
+	return err
+}
+
+func TestScheduleStartsAndStopsStream(t *testing.T) {
+	r := newTestScheduleRunner(t)
+
+	r.tickAt(19, 9, 59)
+	r.tickAt(19, 10, 0)
+	r.tickAt(19, 10, 30)
+	r.tickAt(19, 11, 0)
+	r.tickAt(19, 11, 30)
+
+	if r.starts != 1 || r.stops != 1 {
+		t.Fatalf("starts = %d, stops = %d, want 1 and 1", r.starts, r.stops)
+	}
+	if want := []string{"start:<nil>", "stop:<nil>"}; !slices.Equal(r.records, want) {
+		t.Fatalf("records = %v, want %v", r.records, want)
+	}
+}
+
+func TestScheduleRetriesAndRecordsFailureOnce(t *testing.T) {
+	r := newTestScheduleRunner(t)
+	r.startErrs = []error{errTestStream, errTestStream}
+	r.stopErrs = []error{errTestStream, errTestStream}
+
+	for minute := 0; minute < 4; minute++ {
+		r.tickAt(19, 10, minute)
+	}
+	for minute := 0; minute < 4; minute++ {
+		r.tickAt(19, 11, minute)
+	}
+
+	// Запуск и остановка повторяются до успеха, а ошибка записывается один раз
+	if r.starts != 3 || r.stops != 3 {
+		t.Fatalf("starts = %d, stops = %d, want 3 and 3", r.starts, r.stops)
+	}
+	want := []string{
+		"start:" + errTestStream.Error(),
+		"start:<nil>",
+		"stop:" + errTestStream.Error(),
+		"stop:<nil>",
+	}
+	if !slices.Equal(r.records, want) {
+		t.Fatalf("records = %v, want %v", r.records, want)
+	}
+}
+
+func TestScheduleDoesNotStopManuallyStartedStream(t *testing.T) {
+	r := newTestScheduleRunner(t)
+	r.startErrs = []error{fmt.Errorf("%w: twitch", ErrAlreadyStarted)}
+
+	r.tickAt(19, 10, 0)
+	r.tickAt(19, 10, 30)
+	r.tickAt(19, 11, 0)
+
+	if r.starts != 1 || r.stops != 0 {
+		t.Fatalf("starts = %d, stops = %d, want 1 and 0", r.starts, r.stops)
+	}
+	if len(r.records) > 0 {
+		t.Fatalf("records = %v, want none", r.records)
+	}
+
+	// На следующий день трансляция уже не идет, и расписание снова запускает и останавливает ее само
+	r.tickAt(20, 10, 0)
+	r.tickAt(20, 11, 0)
+	if r.starts != 2 || r.stops != 1 {
+		t.Fatalf("next day starts = %d, stops = %d, want 2 and 1", r.starts, r.stops)
+	}
+}
+
+func TestScheduleRemindsOncePerStart(t *testing.T) {
+	r := newTestScheduleRunner(t)
+	r.missing = []config.Platform{config.PlatformTwitch}
+
+	r.tickAt(19, 9, 0)
+	if len(r.reminders) > 0 {
+		t.Fatalf("reminders = %v before remind_before, want none", r.reminders)
+	}
+
+	r.tickAt(19, 9, 30)
+	r.tickAt(19, 9, 45)
+	r.tickAt(19, 10, 0)
+	r.tickAt(19, 11, 0)
+
+	// Ключ добавили, напоминать не о чем
+	r.missing = nil
+	r.tickAt(20, 9, 40)
+
+	r.missing = []config.Platform{config.PlatformTwitch}
+	r.tickAt(20, 9, 50)
+
+	want := []time.Time{
+		time.Date(2026, time.October, 19, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, time.October, 20, 10, 0, 0, 0, time.UTC),
+	}
+	if !slices.EqualFunc(r.reminders, want, time.Time.Equal) {
+		t.Fatalf("reminders = %v, want %v", r.reminders, want)
+	}
+}
@@ -0,0 +1,344 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/player"
+	"github.com/Perkovec/StatiStream/internal/storage"
+	"github.com/Perkovec/StatiStream/internal/stream"
+)
+
+var (
+	ErrAlreadyStarted  = errors.New("stream is already started on platforms")
+	ErrMissingKeys     = errors.New("stream keys are not set for platforms")
+	ErrUnknownVideo    = storage.ErrUnknownVideo
+	ErrUnknownPlatform = errors.New("unknown platform")
+	ErrEmptyKey        = errors.New("empty stream key")
+	ErrInvalidName     = errors.New("invalid file name")
+)
+
+type PlatformStatus struct {
+	Platform config.Platform `json:"platform"`
+	Started  bool            `json:"started"`
+	HasKey   bool            `json:"has_key"`
+}
+
+type VideoStatus struct {
+	Filename  string    `json:"filename"`
+	Title     string    `json:"title,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+	Duration  float64   `json:"duration"`
+	Size      int64     `json:"size"`
+	Position  int64     `json:"position"`
+	Progress  float64   `json:"progress"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+type Status struct {
+	Started      bool             `json:"started"`
+	Platforms    []PlatformStatus `json:"platforms"`
+	CurrentVideo *VideoStatus     `json:"current_video"`
+	Queue        []string         `json:"queue"`
+}
+
+// Service операции управления трансляцией, общие для телеграмм бота и HTTP API
+type Service struct {
+	videoStorage storage.Storage
+	streams      stream.Streams
+	player       *player.Player
+}
+
+type ServiceParams struct {
+	VideoStorage storage.Storage
+	Streams      stream.Streams
+	Player       *player.Player
+}
+
+func New(params ServiceParams) *Service {
+	return &Service{
+		videoStorage: params.VideoStorage,
+		streams:      params.Streams,
+		player:       params.Player,
+	}
+}
+
+// Platforms возвращает отсортированный список платформ для трансляции
+func (s *Service) Platforms() []config.Platform {
+	platforms := make([]config.Platform, 0, len(s.streams))
+	for platform := range s.streams {
+		platforms = append(platforms, platform)
+	}
+
+	slices.Sort(platforms)
+
+	return platforms
+}
+
+func (s *Service) Status() Status {
+	status := Status{
+		Platforms: make([]PlatformStatus, 0, len(s.streams)),
+		Queue:     s.videoStorage.GetQueue(),
+	}
+
+	if playback := s.player.Playback(); playback != nil {
+		status.CurrentVideo = &VideoStatus{
+			Filename:  playback.Meta.Filename,
+			Title:     playback.Meta.Title,
+			Category:  playback.Meta.Category,
+			Tags:      playback.Meta.Tags,
+			Duration:  playback.Meta.Duration.Seconds(),
+			Size:      playback.Size,
+			Position:  playback.Position,
+			StartedAt: playback.StartedAt,
+		}
+
+		if playback.Size > 0 {
+			status.CurrentVideo.Progress = min(float64(playback.Position)/float64(playback.Size), 1)
+		}
+	}
+
+	for _, platform := range s.Platforms() {
+		platformStream := s.streams[platform]
+		platformStatus := PlatformStatus{
+			Platform: platform,
+			Started:  platformStream.IsStarted(),
+			HasKey:   platformStream.HasToken(),
+		}
+
+		status.Started = status.Started || platformStatus.Started
+		status.Platforms = append(status.Platforms, platformStatus)
+	}
+
+	return status
+}
+
+// StartedPlatforms возвращает платформы, на которых уже идет трансляция
+func (s *Service) StartedPlatforms() []config.Platform {
+	platforms := []config.Platform{}
+	for _, platform := range s.Platforms() {
+		if s.streams[platform].IsStarted() {
+			platforms = append(platforms, platform)
+		}
+	}
+
+	return platforms
+}
+
+// MissingKeyPlatforms возвращает платформы, для которых не установлен ключ трансляции
+func (s *Service) MissingKeyPlatforms() []config.Platform {
+	platforms := []config.Platform{}
+	for _, platform := range s.Platforms() {
+		if !s.streams[platform].HasToken() {
+			platforms = append(platforms, platform)
+		}
+	}
+
+	return platforms
+}
+
+func (s *Service) Start(ctx context.Context) error {
+	if started := s.StartedPlatforms(); len(started) > 0 {
+		return fmt.Errorf("%w: %s", ErrAlreadyStarted, JoinPlatforms(started))
+	}
+
+	if missing := s.MissingKeyPlatforms(); len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingKeys, JoinPlatforms(missing))
+	}
+
+	return s.player.Start(ctx)
+}
+
+func (s *Service) Stop(_ context.Context) error {
+	return s.player.Stop()
+}
+
+func (s *Service) Next(ctx context.Context) error {
+	return s.player.Next(ctx)
+}
+
+func (s *Service) Queue() []string {
+	return s.videoStorage.GetQueue()
+}
+
+func (s *Service) AddToQueue(key string) error {
+	if !slices.Contains(s.videoStorage.GetFilesList(), key) {
+		return fmt.Errorf("%w: %s", ErrUnknownVideo, key)
+	}
+
+	s.videoStorage.AddToQueue(key)
+
+	return nil
+}
+
+func (s *Service) SetQueue(keys []string) error {
+	return s.videoStorage.SetQueue(keys)
+}
+
+func (s *Service) Library() []storage.FileInfo {
+	return s.videoStorage.GetFilesInfo()
+}
+
+func (s *Service) LibraryFiles() []string {
+	return s.videoStorage.GetFilesList()
+}
+
+func (s *Service) ReloadLibrary(ctx context.Context) error {
+	return s.videoStorage.UpdateFilesList(ctx)
+}
+
+// UploadVideo сохраняет видео в хранилище и обновляет библиотеку. Возвращает false, если видео не попало в библиотеку,
+// например если его ключ не входит в папку или префиксы источника
+func (s *Service) UploadVideo(ctx context.Context, key string, video io.ReadSeeker) (bool, error) {
+	err := s.videoStorage.PutVideo(ctx, key, video)
+	if err != nil {
+		return false, fmt.Errorf("Service.UploadVideo.PutVideo: %w", err)
+	}
+
+	err = s.videoStorage.UpdateFilesList(ctx)
+	if err != nil {
+		return false, fmt.Errorf("Service.UploadVideo.UpdateFilesList: %w", err)
+	}
+
+	return slices.Contains(s.videoStorage.GetFilesList(), key), nil
+}
+
+// SearchLibrary ищет видео, которые можно добавить в очередь, лучшие совпадения идут первыми
+func (s *Service) SearchLibrary(query string, limit int) []storage.FileInfo {
+	type result struct {
+		file  storage.FileInfo
+		score int
+	}
+
+	results := []result{}
+	for _, file := range s.videoStorage.GetFilesInfo() {
+		if file.Excluded {
+			continue
+		}
+
+		if score := file.MatchScore(query); score > 0 {
+			results = append(results, result{file: file, score: score})
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b result) int {
+		return b.score - a.score
+	})
+
+	files := make([]storage.FileInfo, 0, min(limit, len(results)))
+	for _, item := range results[:min(limit, len(results))] {
+		files = append(files, item.file)
+	}
+
+	return files
+}
+
+// Video возвращает описание видео из библиотеки, в том числе исключенного
+func (s *Service) Video(key string) (storage.FileInfo, bool) {
+	for _, file := range s.videoStorage.GetFilesInfo() {
+		if file.Key == key {
+			return file, true
+		}
+	}
+
+	return storage.FileInfo{}, false
+}
+
+// DeleteVideo удаляет видео из хранилища и из очереди
+func (s *Service) DeleteVideo(ctx context.Context, key string) error {
+	err := s.videoStorage.DeleteVideo(ctx, key)
+	if err != nil {
+		return fmt.Errorf("Service.DeleteVideo: %w", err)
+	}
+
+	return s.reloadAfterChange(ctx)
+}
+
+// RenameVideo меняет имя файла видео, не меняя папку, и возвращает новый ключ.
+// Если в новом имени нет расширения, то остается прежнее
+func (s *Service) RenameVideo(ctx context.Context, key string, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidName, name)
+	}
+
+	if path.Ext(name) == "" {
+		name += path.Ext(key)
+	}
+
+	newKey := name
+	if index := strings.LastIndexAny(key, "/"+config.SourceNameSeparator); index >= 0 {
+		newKey = key[:index+1] + name
+	}
+
+	if newKey == key {
+		return key, nil
+	}
+
+	err := s.videoStorage.RenameVideo(ctx, key, newKey)
+	if err != nil {
+		return "", fmt.Errorf("Service.RenameVideo: %w", err)
+	}
+
+	return newKey, s.reloadAfterChange(ctx)
+}
+
+// SetVideoMeta меняет название, категорию и теги видео, длительность остается прежней
+func (s *Service) SetVideoMeta(ctx context.Context, key string, title string, category string, tags []string) error {
+	file, ok := s.Video(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownVideo, key)
+	}
+
+	meta := file.Meta
+	meta.Title, meta.Category, meta.Tags = title, category, tags
+
+	err := s.videoStorage.SetVideoMeta(ctx, key, meta)
+	if err != nil {
+		return fmt.Errorf("Service.SetVideoMeta: %w", err)
+	}
+
+	return s.reloadAfterChange(ctx)
+}
+
+// reloadAfterChange обновляет библиотеку после изменения видео, ключи в очереди хранилище заменяет само
+func (s *Service) reloadAfterChange(ctx context.Context) error {
+	err := s.videoStorage.UpdateFilesList(ctx)
+	if err != nil {
+		return fmt.Errorf("Service.UpdateFilesList: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) SetStreamKey(platform config.Platform, key string) error {
+	platformStream, ok := s.streams[platform]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPlatform, platform)
+	}
+
+	if strings.TrimSpace(key) == "" {
+		return ErrEmptyKey
+	}
+
+	platformStream.SetStreamToken(key)
+
+	return nil
+}
+
+func JoinPlatforms(platforms []config.Platform) string {
+	names := make([]string, 0, len(platforms))
+	for _, platform := range platforms {
+		names = append(names, string(platform))
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,347 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	cacheVideoExtension = ".video"
+	cacheEntryExtension = ".json"
+	cacheTempPattern    = "*.tmp"
+)
+
+type CacheParams struct {
+	Directory string
+	// MaxSize максимальный размер кэша в байтах
+	MaxSize int64
+}
+
+// cacheEntry описание видео в кэше, по размеру, ETag и дате изменения проверяется, что видео в хранилище не изменилось
+type cacheEntry struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ETag    string    `json:"etag"`
+	ModTime time.Time `json:"mod_time"`
+
+	lastUsed time.Time
+}
+
+func (e *cacheEntry) matches(file FileInfo) bool {
+	return (file.Size == 0 || file.Size == e.Size) &&
+		(file.ETag == "" || file.ETag == e.ETag) &&
+		(file.ModTime.IsZero() || file.ModTime.Equal(e.ModTime))
+}
+
+// cachedStorage заранее загружает следующее видео в локальную папку, пока играет текущее, и отдает видео из нее,
+// если оно там есть. Старые видео удаляются, когда размер кэша превышает лимит, первыми удаляются давно использованные
+type cachedStorage struct {
+	Storage
+
+	ctx       context.Context
+	directory string
+	maxSize   int64
+
+	mu          sync.Mutex
+	entries     map[string]*cacheEntry
+	prefetching bool
+}
+
+func NewCachedStorage(ctx context.Context, videoStorage Storage, params CacheParams) (Storage, error) {
+	err := os.MkdirAll(params.Directory, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("NewCachedStorage.MkdirAll: %w", err)
+	}
+
+	s := &cachedStorage{
+		Storage:   videoStorage,
+		ctx:       ctx,
+		directory: params.Directory,
+		maxSize:   params.MaxSize,
+		entries:   map[string]*cacheEntry{},
+	}
+
+	err = s.load()
+	if err != nil {
+		return nil, fmt.Errorf("NewCachedStorage.load: %w", err)
+	}
+
+	s.mu.Lock()
+	s.evict("")
+	s.mu.Unlock()
+
+	return s, nil
+}
+
+// GetNextVideo отдает следующее видео и начинает загружать в кэш то, что будет после него
+func (s *cachedStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	video, size, meta, err := getNextVideo(ctx, s)
+	go s.prefetch()
+
+	return video, size, meta, err
+}
+
+func (s *cachedStorage) OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	if video, size, ok := s.openCached(key); ok {
+		zerolog.Ctx(s.ctx).Debug().Str("file", key).Msg("Video served from cache")
+		return video, size, nil
+	}
+
+	return s.Storage.OpenVideo(ctx, key)
+}
+
+func (s *cachedStorage) openCached(key string) (io.ReadCloser, int64, bool) {
+	file, ok := s.fileInfo(key)
+	if !ok {
+		return nil, 0, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return nil, 0, false
+	}
+
+	if !entry.matches(file) {
+		s.remove(key)
+		return nil, 0, false
+	}
+
+	video, size, err := openLocalFile(s.videoPath(key))
+	if err != nil || size != entry.Size {
+		if video != nil {
+			video.Close()
+		}
+		s.remove(key)
+		return nil, 0, false
+	}
+
+	now := time.Now()
+	entry.lastUsed = now
+	os.Chtimes(s.videoPath(key), now, now)
+
+	return video, size, true
+}
+
+// prefetch загружает в кэш видео, которое будет воспроизведено следующим, одновременно идет только одна загрузка
+func (s *cachedStorage) prefetch() {
+	logger := zerolog.Ctx(s.ctx)
+
+	s.mu.Lock()
+	if s.prefetching {
+		s.mu.Unlock()
+		return
+	}
+	s.prefetching = true
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.prefetching = false
+		s.mu.Unlock()
+	}()
+
+	file, ok := s.PeekNextVideo()
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	entry, cached := s.entries[file.Key]
+	s.mu.Unlock()
+	if cached && entry.matches(file) {
+		return
+	}
+
+	if file.Size > s.maxSize {
+		logger.Debug().Str("file", file.Key).Msg("Video is larger than cache, skip prefetch")
+		return
+	}
+
+	start := time.Now()
+	err := s.download(file)
+	if err != nil {
+		logger.Warn().Err(err).Str("file", file.Key).Msg("Unable to prefetch video")
+		return
+	}
+
+	logger.Info().Str("file", file.Key).Dur("duration", time.Since(start)).Msg("Video prefetched to cache")
+}
+
+func (s *cachedStorage) download(file FileInfo) error {
+	video, size, err := s.Storage.OpenVideo(s.ctx, file.Key)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+
+	tmp, err := os.CreateTemp(s.directory, cacheTempPattern)
+	if err != nil {
+		return fmt.Errorf("cachedStorage.download.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	written, err := io.Copy(tmp, video)
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("cachedStorage.download.Copy: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("cachedStorage.download.Close: %w", closeErr)
+	}
+
+	// Проверяем что видео загрузилось целиком
+	for _, expected := range []int64{size, file.Size} {
+		if expected > 0 && written != expected {
+			return fmt.Errorf("cachedStorage.download: size mismatch, expected %d, got %d", expected, written)
+		}
+	}
+
+	entry := &cacheEntry{
+		Key:      file.Key,
+		Size:     written,
+		ETag:     file.ETag,
+		ModTime:  file.ModTime,
+		lastUsed: time.Now(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cachedStorage.download.Marshal: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.Rename(tmp.Name(), s.videoPath(file.Key))
+	if err != nil {
+		return fmt.Errorf("cachedStorage.download.Rename: %w", err)
+	}
+
+	err = os.WriteFile(s.entryPath(file.Key), data, 0o644)
+	if err != nil {
+		os.Remove(s.videoPath(file.Key))
+		return fmt.Errorf("cachedStorage.download.WriteFile: %w", err)
+	}
+
+	s.entries[file.Key] = entry
+	s.evict(file.Key)
+
+	return nil
+}
+
+// load читает описания видео, оставшихся в кэше после прошлого запуска, и удаляет недокачанные файлы
+func (s *cachedStorage) load() error {
+	dirEntries, err := os.ReadDir(s.directory)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, dirEntry := range dirEntries {
+		name := dirEntry.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(s.directory, name))
+			continue
+		}
+
+		if !strings.HasSuffix(name, cacheEntryExtension) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.directory, name))
+		if err != nil {
+			return err
+		}
+
+		entry := &cacheEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			os.Remove(filepath.Join(s.directory, name))
+			continue
+		}
+
+		stat, err := os.Stat(s.videoPath(entry.Key))
+		if err != nil || stat.Size() != entry.Size {
+			s.remove(entry.Key)
+			continue
+		}
+
+		entry.lastUsed = stat.ModTime()
+		s.entries[entry.Key] = entry
+	}
+
+	return nil
+}
+
+// evict удаляет давно использованные видео, пока кэш не поместится в лимит, видео keep не удаляется
+func (s *cachedStorage) evict(keep string) {
+	var total int64
+	for _, entry := range s.entries {
+		total += entry.Size
+	}
+
+	for total > s.maxSize {
+		var oldest *cacheEntry
+		for key, entry := range s.entries {
+			if key != keep && (oldest == nil || entry.lastUsed.Before(oldest.lastUsed)) {
+				oldest = entry
+			}
+		}
+
+		if oldest == nil {
+			return
+		}
+
+		total -= oldest.Size
+		s.remove(oldest.Key)
+	}
+}
+
+func (s *cachedStorage) remove(key string) {
+	os.Remove(s.videoPath(key))
+	os.Remove(s.entryPath(key))
+	delete(s.entries, key)
+}
+
+func (s *cachedStorage) fileInfo(key string) (FileInfo, bool) {
+	for _, file := range s.GetFilesInfo() {
+		if file.Key == key && !file.Excluded {
+			return file, true
+		}
+	}
+
+	return FileInfo{}, false
+}
+
+func (s *cachedStorage) videoPath(key string) string {
+	return filepath.Join(s.directory, cacheName(key)+cacheVideoExtension)
+}
+
+func (s *cachedStorage) entryPath(key string) string {
+	return filepath.Join(s.directory, cacheName(key)+cacheEntryExtension)
+}
+
+func cacheName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *cachedStorage) Unwrap() Storage {
+	return s.Storage
+}
@@ -0,0 +1,194 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+// stubStorage хранилище в памяти, считает открытия видео
+type stubStorage struct {
+	library
+
+	content map[string]string
+	opened  int
+	lastKey string
+}
+
+func newStubStorage(files ...FileInfo) *stubStorage {
+	s := &stubStorage{
+		library: newLibrary(config.PickStrategySequential, nil, nil),
+		content: map[string]string{},
+	}
+	for _, file := range files {
+		s.content[file.Key] = strings.Repeat("v", int(file.Size))
+	}
+	s.setFiles(files)
+
+	return s
+}
+
+func (s *stubStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
+}
+
+func (s *stubStorage) OpenVideo(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	s.opened++
+	s.lastKey = key
+	content := s.content[key]
+
+	return io.NopCloser(strings.NewReader(content)), int64(len(content)), nil
+}
+
+func (s *stubStorage) UpdateFilesList(_ context.Context) error { return nil }
+
+func (s *stubStorage) Ping(_ context.Context) error { return nil }
+
+func newTestCache(t *testing.T, videoStorage Storage, maxSize int64) *cachedStorage {
+	t.Helper()
+
+	cache, err := NewCachedStorage(context.Background(), videoStorage, CacheParams{Directory: t.TempDir(), MaxSize: maxSize})
+	if err != nil {
+		t.Fatalf("NewCachedStorage: %v", err)
+	}
+
+	return cache.(*cachedStorage)
+}
+
+func TestCacheEntryMatches(t *testing.T) {
+	modTime := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	entry := &cacheEntry{Key: "a.ts", Size: 10, ETag: `"v1"`, ModTime: modTime}
+
+	tests := []struct {
+		name string
+		file FileInfo
+		want bool
+	}{
+		{name: "same", file: FileInfo{Key: "a.ts", Size: 10, ETag: `"v1"`, ModTime: modTime}, want: true},
+		{name: "unknown version", file: FileInfo{Key: "a.ts"}, want: true},
+		{name: "size changed", file: FileInfo{Key: "a.ts", Size: 11, ETag: `"v1"`, ModTime: modTime}, want: false},
+		{name: "etag changed", file: FileInfo{Key: "a.ts", Size: 10, ETag: `"v2"`, ModTime: modTime}, want: false},
+		{name: "mod time changed", file: FileInfo{Key: "a.ts", Size: 10, ETag: `"v1"`, ModTime: modTime.Add(time.Second)}, want: false},
+	}
+
+	for _, test := range tests {
+		if got := entry.matches(test.file); got != test.want {
+			t.Errorf("%s: matches() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	files := []FileInfo{{Key: "a.ts", Size: 4}, {Key: "b.ts", Size: 4}, {Key: "c.ts", Size: 4}}
+	cache := newTestCache(t, newStubStorage(files...), 10)
+
+	for _, file := range files[:2] {
+		if err := cache.download(file); err != nil {
+			t.Fatalf("download %s: %v", file.Key, err)
+		}
+	}
+
+	start := time.Now()
+	cache.entries["a.ts"].lastUsed = start.Add(-2 * time.Minute)
+	cache.entries["b.ts"].lastUsed = start.Add(-time.Minute)
+
+	// c.ts не помещается, удаляется давно использованный a.ts
+	if err := cache.download(files[2]); err != nil {
+		t.Fatalf("download c.ts: %v", err)
+	}
+	assertCached(t, cache, map[string]bool{"a.ts": false, "b.ts": true, "c.ts": true})
+
+	// Только что загруженное видео не удаляется, даже если оно использовано раньше остальных
+	cache.entries["c.ts"].lastUsed = start.Add(-time.Hour)
+	cache.maxSize = 4
+	cache.evict("c.ts")
+	assertCached(t, cache, map[string]bool{"b.ts": false, "c.ts": true})
+}
+
+func TestCacheInvalidatesChangedVideo(t *testing.T) {
+	stub := newStubStorage(FileInfo{Key: "a.ts", Size: 4, ETag: `"v1"`})
+	cache := newTestCache(t, stub, 100)
+
+	if err := cache.download(FileInfo{Key: "a.ts", Size: 4, ETag: `"v1"`}); err != nil {
+		t.Fatalf("download: %v", err)
+	}
+
+	video, _, err := cache.OpenVideo(context.Background(), "a.ts")
+	if err != nil {
+		t.Fatalf("OpenVideo: %v", err)
+	}
+	video.Close()
+	if stub.opened != 1 {
+		t.Fatalf("storage opened %d times, want only download", stub.opened)
+	}
+
+	// Видео перезаписали в хранилище, кэш больше не подходит
+	stub.setFiles([]FileInfo{{Key: "a.ts", Size: 4, ETag: `"v2"`}})
+
+	video, _, err = cache.OpenVideo(context.Background(), "a.ts")
+	if err != nil {
+		t.Fatalf("OpenVideo: %v", err)
+	}
+	video.Close()
+	if stub.opened != 2 {
+		t.Fatalf("storage opened %d times, want changed video read from storage", stub.opened)
+	}
+	assertCached(t, cache, map[string]bool{"a.ts": false})
+}
+
+func TestCacheLoadRemovesBrokenEntries(t *testing.T) {
+	directory := t.TempDir()
+	cache := &cachedStorage{directory: directory}
+
+	writeEntry := func(key string, entrySize int, videoSize int) {
+		data, err := json.Marshal(cacheEntry{Key: key, Size: int64(entrySize)})
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if err := os.WriteFile(cache.entryPath(key), data, 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+		if err := os.WriteFile(cache.videoPath(key), []byte(strings.Repeat("v", videoSize)), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	writeEntry("good.ts", 4, 4)
+	writeEntry("short.ts", 4, 2)
+	tmp := filepath.Join(directory, "123.tmp")
+	if err := os.WriteFile(tmp, []byte("partial"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	loaded, err := NewCachedStorage(context.Background(), newStubStorage(), CacheParams{Directory: directory, MaxSize: 100})
+	if err != nil {
+		t.Fatalf("NewCachedStorage: %v", err)
+	}
+
+	assertCached(t, loaded.(*cachedStorage), map[string]bool{"good.ts": true, "short.ts": false})
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatalf("temporary file is not removed: %v", err)
+	}
+}
+
+// assertCached проверяет, что видео есть или нет в кэше вместе с файлами на диске
+func assertCached(t *testing.T, cache *cachedStorage, want map[string]bool) {
+	t.Helper()
+
+	for key, cached := range want {
+		_, inEntries := cache.entries[key]
+		_, videoErr := os.Stat(cache.videoPath(key))
+		_, entryErr := os.Stat(cache.entryPath(key))
+
+		if inEntries != cached || (videoErr == nil) != cached || (entryErr == nil) != cached {
+			t.Errorf("%s cached = %v, files %v %v, want %v", key, inEntries, videoErr, entryErr, cached)
+		}
+	}
+}
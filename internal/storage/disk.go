@@ -2,35 +2,433 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
 )
 
 type DiskStorageParams struct {
+	PickStrategy  config.PickStrategy
+	Schedule      *schedule.Schedule
+	DirectoryPath string
+	Files         []string
+	// Extensions допустимые расширения видео, по умолчанию .ts
+	Extensions []string
+	// Watch следить за изменениями в папке и добавлять новые видео без перезагрузки данных
+	Watch bool
+	// QuietPeriod сколько файл не должен изменяться, чтобы считаться записанным, по умолчанию 10 секунд
+	QuietPeriod time.Duration
 }
 
 type diskStorage struct {
+	library
+
+	directoryPath string
+	files         []string
+	watchEnabled  bool
+	quietPeriod   time.Duration
+	// checkInterval и pollInterval интервалы проверки записанных файлов и опроса папки без fsnotify
+	checkInterval time.Duration
+	pollInterval  time.Duration
+
+	// uploaded дата изменения видео, записанных через PutVideo, они записаны целиком и не ждут quietPeriod
+	uploadedMu sync.Mutex
+	uploaded   map[string]time.Time
 }
 
-func NewDiskStorage(params DiskStorageParams) Storage {
-	return &diskStorage{}
+func NewDiskStorage(ctx context.Context, params DiskStorageParams) (Storage, error) {
+	st := &diskStorage{
+		library:       newLibrary(params.PickStrategy, params.Schedule, params.Extensions),
+		directoryPath: params.DirectoryPath,
+		files:         params.Files,
+		watchEnabled:  params.Watch,
+		quietPeriod:   params.QuietPeriod,
+		checkInterval: watchCheckInterval,
+		pollInterval:  watchPollInterval,
+		uploaded:      map[string]time.Time{},
+	}
+	if st.quietPeriod <= 0 {
+		st.quietPeriod = defaultWatchQuietPeriod
+	}
+
+	err := st.UpdateFilesList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("DiskStorage.UpdateFilesList: %w", err)
+	}
+
+	return st, nil
 }
 
-func (s *diskStorage) GetNextVideo() (io.ReadCloser, int64, *VideoMeta) {
-	return nil, 0, nil
+func (s *diskStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
 }
 
-func (s *diskStorage) UpdateFilesList(_ context.Context) error {
+func (s *diskStorage) OpenVideo(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	f, size, err := openLocalFile(s.filePath(key))
+	if err != nil {
+		return nil, 0, fmt.Errorf("DiskStorage.OpenVideo: %w", err)
+	}
+
+	return f, size, nil
+}
+
+// PutVideo записывает видео во временный файл и переносит его под ключ, чтобы в папке не появлялся недописанный файл.
+// Существующее видео не заменяется
+func (s *diskStorage) PutVideo(_ context.Context, key string, video io.ReadSeeker) error {
+	if len(s.files) > 0 || s.directoryPath == "" {
+		return ErrReadOnly
+	}
+
+	path := s.filePath(key)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("DiskStorage.PutVideo: %w: %s", ErrVideoExists, key)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.MkdirAll: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.CreateTemp: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, video)
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.Copy: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.Close: %w", closeErr)
+	}
+
+	// Временный файл создается с правами только для владельца
+	err = os.Chmod(tmp.Name(), 0o644)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.Chmod: %w", err)
+	}
+
+	// Link, в отличие от Rename, не заменяет файл, который успели загрузить под тем же именем, пока шла запись
+	err = os.Link(tmp.Name(), path)
+	if errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("DiskStorage.PutVideo: %w: %s", ErrVideoExists, key)
+	}
+	if err != nil {
+		return fmt.Errorf("DiskStorage.PutVideo.Link: %w", err)
+	}
+
+	if stat, err := os.Stat(path); err == nil {
+		s.uploadedMu.Lock()
+		s.uploaded[key] = stat.ModTime()
+		s.uploadedMu.Unlock()
+	}
+
+	return nil
+}
+
+func (s *diskStorage) DeleteVideo(_ context.Context, key string) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	err := os.Remove(s.filePath(key))
+	if err != nil {
+		return fmt.Errorf("DiskStorage.DeleteVideo.Remove: %w", err)
+	}
+
+	err = os.Remove(s.filePath(metaKey(key)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("DiskStorage.DeleteVideo.RemoveMeta: %w", err)
+	}
+
+	s.renameQueued(key, "")
+
 	return nil
 }
 
-func (s *diskStorage) GetQueue() []string {
-	return []string{}
+func (s *diskStorage) RenameVideo(_ context.Context, key string, newKey string) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	newPath := s.filePath(newKey)
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("DiskStorage.RenameVideo: %w: %s", ErrVideoExists, newKey)
+	}
+
+	err := os.MkdirAll(filepath.Dir(newPath), 0o755)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.RenameVideo.MkdirAll: %w", err)
+	}
+
+	err = os.Rename(s.filePath(key), newPath)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.RenameVideo.Rename: %w", err)
+	}
+	s.renameQueued(key, newKey)
+
+	err = os.Rename(s.filePath(metaKey(key)), s.filePath(metaKey(newKey)))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("DiskStorage.RenameVideo.RenameMeta: %w", err)
+	}
+
+	return nil
 }
 
-func (s *diskStorage) AddToQueue(_ string) {
+func (s *diskStorage) SetVideoMeta(_ context.Context, key string, meta VideoMeta) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	data, err := marshalVideoMeta(meta)
+	if err != nil {
+		return err
+	}
 
+	err = os.WriteFile(s.filePath(metaKey(key)), data, 0o644)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.SetVideoMeta.WriteFile: %w", err)
+	}
+
+	return nil
 }
 
-func (s *diskStorage) GetFilesList() []string {
-	return []string{}
+func (s *diskStorage) UpdateFilesList(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	keys := s.files
+	if len(keys) == 0 {
+		var err error
+		keys, err = s.walkDirectory()
+		if err != nil {
+			return fmt.Errorf("DiskStorage.UpdateFilesList.walkDirectory: %w", err)
+		}
+	}
+
+	files := make([]FileInfo, 0, len(keys))
+	videoList := make([]string, 0, len(keys))
+	for _, key := range keys {
+		file := FileInfo{
+			Key:  key,
+			Meta: VideoMeta{Filename: key},
+		}
+
+		stat, err := os.Stat(s.filePath(key))
+		switch {
+		case err != nil:
+			file.Excluded = true
+			file.Reason = "file not found"
+		case stat.IsDir():
+			file.Excluded = true
+			file.Reason = "is a directory"
+		case !s.isVideo(key):
+			file.Size = stat.Size()
+			file.Excluded = true
+			file.Reason = "unsupported extension"
+		case s.watchEnabled && time.Since(stat.ModTime()) < s.quietPeriod && !s.isUploaded(key, stat.ModTime()):
+			// Файл еще записывается, он будет добавлен, когда перестанет изменяться
+			file.Size = stat.Size()
+			file.ModTime = stat.ModTime()
+			file.Excluded = true
+			file.Reason = "file is being written"
+		default:
+			file.Size = stat.Size()
+			file.ModTime = stat.ModTime()
+			videoList = append(videoList, key)
+		}
+
+		meta, err := s.readMeta(key)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn().Err(err).Str("file", key).Msg("Unable to read video metadata")
+		} else if err == nil {
+			file.Meta = meta
+		}
+
+		files = append(files, file)
+	}
+
+	// Явный список файлов воспроизводится в указанном порядке, а найденные в папке - по дате изменения
+	if len(s.files) == 0 {
+		sortByModTime(files)
+	}
+
+	s.setFiles(files)
+
+	logger.Info().
+		Strs("files", videoList).
+		Msg("Files list updated")
+
+	return nil
+}
+
+func (s *diskStorage) isUploaded(key string, modTime time.Time) bool {
+	s.uploadedMu.Lock()
+	defer s.uploadedMu.Unlock()
+
+	return s.uploaded[key].Equal(modTime)
+}
+
+// Ping проверяет доступность папки с видеозаписями
+func (s *diskStorage) Ping(_ context.Context) error {
+	if s.directoryPath == "" {
+		return nil
+	}
+
+	_, err := os.Stat(s.directoryPath)
+	if err != nil {
+		return fmt.Errorf("DiskStorage.Ping.Stat: %w", err)
+	}
+
+	return nil
+}
+
+func (s *diskStorage) walkDirectory() ([]string, error) {
+	keys := []string{}
+	err := filepath.WalkDir(s.directoryPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || isMetaKey(path) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.directoryPath, path)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, filepath.ToSlash(rel))
+
+		return nil
+	})
+
+	return keys, err
+}
+
+func (s *diskStorage) filePath(key string) string {
+	if filepath.IsAbs(key) {
+		return key
+	}
+
+	return filepath.Join(s.directoryPath, filepath.FromSlash(key))
+}
+
+func (s *diskStorage) readMeta(videoKey string) (VideoMeta, error) {
+	data, err := os.ReadFile(s.filePath(metaKey(videoKey)))
+	if err != nil {
+		return VideoMeta{Filename: videoKey}, err
+	}
+
+	return parseVideoMeta(videoKey, data)
+}
+
+func (s *diskStorage) watching() bool {
+	return s.watchEnabled
+}
+
+// watch следит за папкой через fsnotify и перечитывает список видео, когда измененные файлы перестают изменяться
+// на время quietPeriod. Если fsnotify недоступен, то папка опрашивается с интервалом
+func (s *diskStorage) watch(ctx context.Context, changed func()) {
+	logger := zerolog.Ctx(ctx)
+
+	watcher, err := s.newWatcher()
+	if err != nil {
+		logger.Warn().Err(err).Msg("Unable to watch directory, falling back to polling")
+		relist(ctx, s, s.pollInterval, changed)
+		return
+	}
+	defer watcher.Close()
+
+	ticker := time.NewTicker(s.checkInterval)
+	defer ticker.Stop()
+
+	// pending время последнего события для каждого измененного пути, папка добавлена, чтобы после запуска
+	// перечитать список и добавить файлы, которые при запуске еще записывались
+	pending := map[string]time.Time{s.directoryPath: time.Now()}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			pending[event.Name] = time.Now()
+
+			// Новые вложенные папки тоже отслеживаем
+			if event.Has(fsnotify.Create) {
+				if stat, err := os.Stat(event.Name); err == nil && stat.IsDir() {
+					if err := addWatchDirs(watcher, event.Name); err != nil {
+						logger.Warn().Err(err).Str("path", event.Name).Msg("Unable to watch directory")
+					}
+				}
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.Warn().Err(err).Msg("Directory watcher error")
+		case <-ticker.C:
+			settled := false
+			for path, last := range pending {
+				if time.Since(last) >= s.quietPeriod {
+					delete(pending, path)
+					settled = true
+				}
+			}
+
+			if settled {
+				reload(ctx, s, changed)
+			}
+		}
+	}
+}
+
+func (s *diskStorage) newWatcher() (*fsnotify.Watcher, error) {
+	if s.directoryPath == "" {
+		return nil, errors.New("directory path is not specified")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("DiskStorage.newWatcher.NewWatcher: %w", err)
+	}
+
+	err = addWatchDirs(watcher, s.directoryPath)
+	if err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("DiskStorage.newWatcher.addWatchDirs: %w", err)
+	}
+
+	return watcher, nil
+}
+
+// addWatchDirs добавляет в watcher папку со всеми вложенными папками, fsnotify не следит за ними сам
+func addWatchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.IsDir() {
+			return nil
+		}
+
+		return watcher.Add(path)
+	})
 }
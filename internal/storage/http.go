@@ -0,0 +1,374 @@
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/rs/zerolog"
+)
+
+var (
+	errUnexpectedStatus = errors.New("unexpected status")
+	errFileChanged      = errors.New("file changed or range not supported")
+)
+
+// hrefPattern ссылки из HTML страницы со списком файлов, которую отдают nginx autoindex, Apache и подобные серверы
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*"([^"]+)"`)
+
+type HTTPStorageParams struct {
+	PickStrategy config.PickStrategy
+	Schedule     *schedule.Schedule
+	// URL адрес страницы со списком файлов или JSON списка, пути видео считаются относительно него
+	URL   string
+	Files []string
+	// Extensions допустимые расширения видео, по умолчанию .ts
+	Extensions []string
+	// Client необязательно, по умолчанию клиент с таймаутами ожидания ответа и простоя соединения
+	Client *http.Client
+}
+
+// httpListingEntry запись JSON списка файлов, name и type заполняет nginx с autoindex_format json
+type httpListingEntry struct {
+	playlistFileEntry
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+}
+
+// httpListing JSON список файлов в виде объекта с полем files
+type httpListing struct {
+	Files json.RawMessage `json:"files"`
+}
+
+// httpStorage берет видео с HTTP сервера или CDN
+type httpStorage struct {
+	library
+
+	url    *url.URL
+	files  []string
+	client *http.Client
+}
+
+func NewHTTPStorage(ctx context.Context, params HTTPStorageParams) (Storage, error) {
+	listURL, err := url.Parse(params.URL)
+	if err != nil {
+		return nil, fmt.Errorf("NewHTTPStorage.Parse: %w", err)
+	}
+
+	client := params.Client
+	if client == nil {
+		client = newStreamingClient()
+	}
+
+	st := &httpStorage{
+		library: newLibrary(params.PickStrategy, params.Schedule, params.Extensions),
+		url:     listURL,
+		files:   params.Files,
+		client:  client,
+	}
+
+	err = st.UpdateFilesList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("HTTPStorage.UpdateFilesList: %w", err)
+	}
+
+	return st, nil
+}
+
+func (s *httpStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
+}
+
+func (s *httpStorage) OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	video, size, err := openHTTP(ctx, s.client, s.resolve(key))
+	if err != nil {
+		return nil, 0, fmt.Errorf("HTTPStorage.OpenVideo: %w", err)
+	}
+
+	return video, size, nil
+}
+
+func (s *httpStorage) UpdateFilesList(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	entries := make([]httpListingEntry, 0, len(s.files))
+	for _, key := range s.files {
+		entries = append(entries, httpListingEntry{playlistFileEntry: playlistFileEntry{Path: key}})
+	}
+
+	if len(entries) == 0 {
+		var err error
+		entries, err = s.list(ctx)
+		if err != nil {
+			return fmt.Errorf("HTTPStorage.UpdateFilesList.list: %w", err)
+		}
+	}
+
+	metaKeys := make(map[string]struct{})
+	for _, entry := range entries {
+		if isMetaKey(entry.Path) {
+			metaKeys[entry.Path] = struct{}{}
+		}
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	videoList := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		key := entry.Path
+		if isMetaKey(key) {
+			continue
+		}
+
+		file := FileInfo{
+			Key:  key,
+			Size: entry.Size,
+			Meta: VideoMeta{
+				Filename: key,
+				Title:    entry.Title,
+				Category: entry.Category,
+				Tags:     entry.Tags,
+				Duration: time.Duration(entry.Duration * float64(time.Second)),
+			},
+		}
+
+		if !s.isVideo(key) {
+			file.Excluded = true
+			file.Reason = "unsupported extension"
+		} else {
+			videoList = append(videoList, key)
+		}
+
+		if _, ok := metaKeys[metaKey(key)]; ok {
+			meta, err := s.readMeta(ctx, key)
+			if err != nil {
+				logger.Warn().Err(err).Str("file", key).Msg("Unable to read video metadata")
+			} else {
+				file.Meta = meta
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	s.setFiles(files)
+
+	logger.Info().
+		Strs("files", videoList).
+		Msg("Files list updated")
+
+	return nil
+}
+
+// Ping проверяет доступность адреса со списком файлов
+func (s *httpStorage) Ping(ctx context.Context) error {
+	body, err := s.get(ctx, s.url.String())
+	if err != nil {
+		return fmt.Errorf("HTTPStorage.Ping: %w", err)
+	}
+
+	return body.Close()
+}
+
+// list получает список файлов из JSON (массив путей или записей с path, size, title, category, tags, duration,
+// либо объект с таким массивом в поле files) или из HTML страницы со ссылками, из HTML берутся только файлы из той же папки без вложенных папок
+func (s *httpStorage) list(ctx context.Context) ([]httpListingEntry, error) {
+	body, err := s.get(ctx, s.url.String())
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("HTTPStorage.list.ReadAll: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONListing(trimmed)
+	}
+
+	return s.parseHTMLListing(data), nil
+}
+
+func parseJSONListing(data []byte) ([]httpListingEntry, error) {
+	if data[0] == '{' {
+		var listing httpListing
+		if err := json.Unmarshal(data, &listing); err != nil {
+			return nil, fmt.Errorf("parseJSONListing.Unmarshal: %w", err)
+		}
+
+		if len(listing.Files) == 0 {
+			return nil, errors.New("parseJSONListing: object has no files field")
+		}
+
+		data = listing.Files
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err == nil {
+		entries := make([]httpListingEntry, 0, len(paths))
+		for _, path := range paths {
+			entries = append(entries, httpListingEntry{playlistFileEntry: playlistFileEntry{Path: path}})
+		}
+
+		return entries, nil
+	}
+
+	var listed []httpListingEntry
+	if err := json.Unmarshal(data, &listed); err != nil {
+		return nil, fmt.Errorf("parseJSONListing.Unmarshal: %w", err)
+	}
+
+	entries := make([]httpListingEntry, 0, len(listed))
+	for i, entry := range listed {
+		// Вложенные папки из списка nginx пропускаем так же, как в HTML
+		if entry.Type == "directory" {
+			continue
+		}
+
+		if len(entry.Path) == 0 {
+			entry.Path = entry.Name
+		}
+
+		if len(entry.Path) == 0 {
+			return nil, fmt.Errorf("parseJSONListing: entry %d has no path", i)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (s *httpStorage) parseHTMLListing(data []byte) []httpListingEntry {
+	entries := []httpListingEntry{}
+	seen := map[string]struct{}{}
+	for _, match := range hrefPattern.FindAllSubmatch(data, -1) {
+		href := string(match[1])
+		// Пропускаем сортировку, ссылки на родительскую и вложенные папки и ссылки на другие сайты
+		if strings.ContainsAny(href, "?#") || strings.HasSuffix(href, "/") || strings.Contains(href, "://") {
+			continue
+		}
+
+		key, err := url.PathUnescape(strings.TrimPrefix(href, "./"))
+		if err != nil || strings.Contains(key, "/") {
+			continue
+		}
+
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		entries = append(entries, httpListingEntry{playlistFileEntry: playlistFileEntry{Path: key}})
+	}
+
+	return entries
+}
+
+func (s *httpStorage) readMeta(ctx context.Context, videoKey string) (VideoMeta, error) {
+	body, err := s.get(ctx, s.resolve(metaKey(videoKey)))
+	if err != nil {
+		return VideoMeta{Filename: videoKey}, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return VideoMeta{Filename: videoKey}, fmt.Errorf("HTTPStorage.readMeta.ReadAll: %w", err)
+	}
+
+	return parseVideoMeta(videoKey, data)
+}
+
+// resolve возвращает адрес файла: абсолютные адреса остаются как есть, остальные считаются от адреса списка
+func (s *httpStorage) resolve(key string) string {
+	if isRemoteLocation(key) {
+		return key
+	}
+
+	return s.url.ResolveReference(&url.URL{Path: key}).String()
+}
+
+func (s *httpStorage) get(ctx context.Context, location string) (io.ReadCloser, error) {
+	res, err := httpGet(ctx, s.client, location, 0, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Body, nil
+}
+
+// openHTTP открывает видео по HTTP, при обрыве соединения чтение продолжается запросом с заголовком Range.
+// Запрос продолжения отправляется с If-Range, чтобы не склеить две разные версии файла
+func openHTTP(ctx context.Context, client *http.Client, location string) (io.ReadCloser, int64, error) {
+	res, err := httpGet(ctx, client, location, 0, "")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size := max(res.ContentLength, 0)
+	// Слабый ETag для If-Range не подходит, тогда сравниваем по дате изменения
+	validator := res.Header.Get("ETag")
+	if strings.HasPrefix(validator, "W/") {
+		validator = ""
+	}
+	validator = cmp.Or(validator, res.Header.Get("Last-Modified"))
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		res, err := httpGet(ctx, client, location, offset, validator)
+		if err != nil {
+			return nil, err
+		}
+
+		// Целиком файл отдается, если он изменился или сервер не поддерживает Range, продолжать нельзя
+		if res.StatusCode != http.StatusPartialContent {
+			res.Body.Close()
+			return nil, fmt.Errorf("openHTTP: %w: resume at offset %d", errFileChanged, offset)
+		}
+
+		return res.Body, nil
+	}
+
+	return newResumingReader(ctx, res.Body, size, open), size, nil
+}
+
+// httpGet делает GET запрос, с offset больше нуля запрашивает файл начиная с этого байта,
+// ifRange необязательно, ETag или Last-Modified версии файла, продолжение которой запрашивается
+func httpGet(ctx context.Context, client *http.Client, location string, offset int64, ifRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, fmt.Errorf("httpGet.NewRequest: %w", err)
+	}
+
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if len(ifRange) > 0 {
+			req.Header.Set("If-Range", ifRange)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("httpGet.Do: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusPartialContent {
+		res.Body.Close()
+		return nil, fmt.Errorf("httpGet: %w: %s", errUnexpectedStatus, res.Status)
+	}
+
+	return res, nil
+}
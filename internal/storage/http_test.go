@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+func newTestHTTPStorage(t *testing.T, listURL string) *httpStorage {
+	t.Helper()
+
+	st, err := NewHTTPStorage(context.Background(), HTTPStorageParams{
+		PickStrategy: config.PickStrategySequential,
+		URL:          listURL,
+	})
+	if err != nil {
+		t.Fatalf("NewHTTPStorage: %v", err)
+	}
+
+	return st.(*httpStorage)
+}
+
+func TestHTTPStorageDirectoryIndex(t *testing.T) {
+	index := `<html><body>
+<a href="?C=N;O=D">Name</a>
+<a href="../">Parent</a>
+<a href="nested/">nested/</a>
+<a href="video%201.ts">video 1.ts</a>
+<a href="./video2.ts">video2.ts</a>
+<a href="video2.ts">video2.ts</a>
+<a href="notes.txt">notes.txt</a>
+<a href="https://example.com/other.ts">other</a>
+</body></html>`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, index)
+	}))
+	defer server.Close()
+
+	st := newTestHTTPStorage(t, server.URL+"/videos/")
+
+	files := st.GetFilesList()
+	if want := []string{"video 1.ts", "video2.ts"}; !slices.Equal(files, want) {
+		t.Fatalf("GetFilesList() = %q, want %q", files, want)
+	}
+
+	if got := st.resolve("video 1.ts"); got != server.URL+"/videos/video%201.ts" {
+		t.Fatalf("resolve() = %q", got)
+	}
+}
+
+func TestHTTPStorageJSONListing(t *testing.T) {
+	tests := []struct {
+		name    string
+		listing string
+		want    []string
+		size    int64
+	}{
+		{
+			name:    "paths",
+			listing: `["a.ts", "b.ts"]`,
+			want:    []string{"a.ts", "b.ts"},
+		},
+		{
+			name:    "entries",
+			listing: `[{"path": "a.ts", "size": 42, "title": "A"}, {"path": "b.ts"}]`,
+			want:    []string{"a.ts", "b.ts"},
+			size:    42,
+		},
+		{
+			name:    "object",
+			listing: `{"files": [{"path": "a.ts", "size": 42}, {"path": "b.ts"}]}`,
+			want:    []string{"a.ts", "b.ts"},
+			size:    42,
+		},
+		{
+			name:    "nginx",
+			listing: `[{"name": "nested", "type": "directory"}, {"name": "a.ts", "type": "file", "size": 42}, {"name": "b.ts", "type": "file", "size": 7}]`,
+			want:    []string{"a.ts", "b.ts"},
+			size:    42,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, test.listing)
+			}))
+			defer server.Close()
+
+			st := newTestHTTPStorage(t, server.URL+"/list.json")
+
+			files := st.GetFilesList()
+			if !slices.Equal(files, test.want) {
+				t.Fatalf("GetFilesList() = %q, want %q", files, test.want)
+			}
+
+			info := st.GetFilesInfo()
+			if info[0].Size != test.size {
+				t.Fatalf("Size = %d, want %d", info[0].Size, test.size)
+			}
+		})
+	}
+}
+
+func TestHTTPStorageJSONListingWithoutPath(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `[{"title": "no path"}]`)
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPStorage(context.Background(), HTTPStorageParams{
+		PickStrategy: config.PickStrategySequential,
+		URL:          server.URL,
+	})
+	if err == nil {
+		t.Fatal("NewHTTPStorage() error = nil, want entry without path error")
+	}
+}
+
+func TestHTTPStorageOpenVideoSize(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/list.json" {
+			io.WriteString(w, `["video.ts"]`)
+			return
+		}
+
+		http.ServeContent(w, r, "video.ts", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	st := newTestHTTPStorage(t, server.URL+"/list.json")
+
+	video, size, err := st.OpenVideo(context.Background(), "video.ts")
+	if err != nil {
+		t.Fatalf("OpenVideo: %v", err)
+	}
+	defer video.Close()
+
+	if size != int64(len(content)) {
+		t.Fatalf("size = %d, want %d", size, len(content))
+	}
+
+	data, err := io.ReadAll(video)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if !bytes.Equal(data, content) {
+		t.Fatal("video content differs")
+	}
+}
+
+// droppingServer отдает видео, но первый ответ обрывает на середине тела.
+// etag возвращает ETag для очередного запроса, так можно изменить файл между запросами
+func droppingServer(t *testing.T, content []byte, etag func(request int) string) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var requests atomic.Int32
+	ranges := &[]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := int(requests.Add(1))
+		*ranges = append(*ranges, r.Header.Get("Range")+"|"+r.Header.Get("If-Range"))
+
+		w.Header().Set("ETag", etag(request))
+
+		if request == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "video.ts", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, ranges
+}
+
+func TestOpenHTTPResumesAfterDrop(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	server, ranges := droppingServer(t, content, func(int) string { return `"v1"` })
+
+	video, size, err := openHTTP(context.Background(), server.Client(), server.URL+"/video.ts")
+	if err != nil {
+		t.Fatalf("openHTTP: %v", err)
+	}
+	defer video.Close()
+
+	if size != int64(len(content)) {
+		t.Fatalf("size = %d, want %d", size, len(content))
+	}
+
+	data, err := io.ReadAll(video)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if !bytes.Equal(data, content) {
+		t.Fatalf("read %d bytes, content differs from the original %d bytes", len(data), len(content))
+	}
+
+	if len(*ranges) != 2 || !strings.HasPrefix((*ranges)[1], "bytes=") || !strings.HasSuffix((*ranges)[1], `|"v1"`) {
+		t.Fatalf("requests = %q, want a resume with Range and If-Range", *ranges)
+	}
+}
+
+func TestOpenHTTPFailsWhenFileChanged(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	server, ranges := droppingServer(t, content, func(request int) string {
+		if request == 1 {
+			return `"v1"`
+		}
+
+		return `"v2"`
+	})
+
+	video, _, err := openHTTP(context.Background(), server.Client(), server.URL+"/video.ts")
+	if err != nil {
+		t.Fatalf("openHTTP: %v", err)
+	}
+	defer video.Close()
+
+	_, err = io.ReadAll(video)
+	if !errors.Is(err, errFileChanged) {
+		t.Fatalf("ReadAll() error = %v, want %v", err, errFileChanged)
+	}
+
+	// После ответа 200 на запрос с Range повторять бесполезно
+	if len(*ranges) != 2 {
+		t.Fatalf("requests = %q, want exactly one resume attempt", *ranges)
+	}
+}
+
+func TestOpenHTTPResumesAfterStall(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	release := make(chan struct{})
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+
+		if requests.Add(1) == 1 {
+			// Соединение зависает посреди тела без ошибки
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+
+		http.ServeContent(w, r, "video.ts", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	video, _, err := openHTTP(context.Background(), newStreamingClient(), server.URL+"/video.ts")
+	if err != nil {
+		t.Fatalf("openHTTP: %v", err)
+	}
+	defer video.Close()
+
+	video.(*resumingReader).stallTimeout = 100 * time.Millisecond
+
+	data, err := io.ReadAll(video)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+
+	if !bytes.Equal(data, content) {
+		t.Fatalf("read %d bytes, content differs from the original %d bytes", len(data), len(content))
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+}
@@ -0,0 +1,384 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+)
+
+const metaExtension = ".meta.json"
+
+// defaultVideoExtensions расширения видео, если они не указаны в конфигурации
+var defaultVideoExtensions = []string{".ts"}
+
+var (
+	ErrUnknownVideo = errors.New("unknown video")
+	ErrNoVideo      = errors.New("no video to stream")
+	ErrReadOnly     = errors.New("storage does not support changes")
+	ErrVideoExists  = errors.New("video already exists")
+)
+
+// library хранит общий для всех хранилищ список видеозаписей, очередь и логику выбора следующего видео
+type library struct {
+	mu sync.RWMutex
+
+	pickStrategy config.PickStrategy
+	schedule     *schedule.Schedule
+	extensions   []string
+	files        []FileInfo
+	queue        []string
+	// lastPicked последнее выбранное видео для последовательной стратегии, отдельно для каждого слота сетки
+	lastPicked map[string]string
+	// upcoming видео, выбранное заранее через PeekNextVideo, вместе со слотом, в котором его выбрали
+	upcoming     string
+	upcomingSlot string
+	// sources источники объединенной библиотеки, ключи видео в ней начинаются с имени источника
+	sources map[string]librarySource
+}
+
+// librarySource вес источника объединенной библиотеки и стратегия выбора видео внутри него
+type librarySource struct {
+	weight       int
+	pickStrategy config.PickStrategy
+}
+
+func newLibrary(pickStrategy config.PickStrategy, videoSchedule *schedule.Schedule, extensions []string) library {
+	if len(extensions) == 0 {
+		extensions = defaultVideoExtensions
+	}
+
+	return library{
+		pickStrategy: pickStrategy,
+		schedule:     videoSchedule,
+		extensions:   extensions,
+		queue:        []string{},
+		lastPicked:   map[string]string{},
+	}
+}
+
+// isVideo проверяет расширение файла без учета регистра, у адресов параметры запроса не учитываются
+func (l *library) isVideo(key string) bool {
+	key = strings.ToLower(locationPath(key))
+
+	return slices.ContainsFunc(l.extensions, func(extension string) bool {
+		return strings.HasSuffix(key, strings.ToLower(extension))
+	})
+}
+
+// sortByModTime упорядочивает видео по дате изменения для последовательной стратегии
+func sortByModTime(files []FileInfo) {
+	slices.SortStableFunc(files, func(a, b FileInfo) int {
+		return a.ModTime.Compare(b.ModTime)
+	})
+}
+
+// setFiles заменяет список видео, видео, которых больше нет в библиотеке, убираются из очереди
+func (l *library) setFiles(files []FileInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.files = files
+	l.queue = slices.DeleteFunc(l.queue, func(key string) bool {
+		_, err := l.findValid(key)
+		return err != nil
+	})
+}
+
+// renameQueued заменяет ключ видео в очереди после переименования, пустой newKey убирает видео из очереди
+func (l *library) renameQueued(key string, newKey string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queue := make([]string, 0, len(l.queue))
+	for _, item := range l.queue {
+		if item != key {
+			queue = append(queue, item)
+		} else if newKey != "" {
+			queue = append(queue, newKey)
+		}
+	}
+	l.queue = queue
+
+	if l.upcoming == key {
+		l.upcoming, l.upcomingSlot = "", ""
+	}
+}
+
+// PickNextVideo выбирает следующее видео: сначала из очереди, затем выбранное заранее, затем по стратегии.
+// Видео из очереди, которых уже нет в библиотеке, пропускаются
+func (l *library) PickNextVideo() (FileInfo, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.queue) > 0 {
+		var key string
+		key, l.queue = l.queue[0], l.queue[1:]
+
+		if file, err := l.findValid(key); err == nil {
+			return file, nil
+		}
+	}
+
+	// Выбранное заранее видео не используем, если с тех пор начался другой слот или видео пропало из библиотеки
+	upcoming, upcomingSlot := l.upcoming, l.upcomingSlot
+	l.upcoming, l.upcomingSlot = "", ""
+	if upcoming != "" && upcomingSlot == l.currentSlotName() {
+		if file, err := l.findValid(upcoming); err == nil {
+			return file, nil
+		}
+	}
+
+	key, _ := l.pickByStrategy()
+
+	return l.findValid(key)
+}
+
+// PeekNextVideo возвращает видео, которое скорее всего вернет следующий PickNextVideo, чтобы его можно было
+// загрузить заранее. Выбор запоминается, но может измениться, если до этого в очередь добавят видео или сменится слот
+func (l *library) PeekNextVideo() (FileInfo, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range l.queue {
+		if file, err := l.findValid(key); err == nil {
+			return file, true
+		}
+	}
+
+	if l.upcoming == "" || l.upcomingSlot != l.currentSlotName() {
+		l.upcoming, l.upcomingSlot = l.pickByStrategy()
+	}
+
+	file, err := l.findValid(l.upcoming)
+
+	return file, err == nil
+}
+
+func (l *library) findValid(key string) (FileInfo, error) {
+	for _, file := range l.files {
+		if file.Key == key && !file.Excluded {
+			return file, nil
+		}
+	}
+
+	return FileInfo{}, ErrNoVideo
+}
+
+func (l *library) currentSlotName() string {
+	if slot := l.schedule.Current(time.Now()); slot != nil {
+		return slot.Name
+	}
+
+	return ""
+}
+
+// pickByStrategy выбирает видео по стратегии текущего слота сетки вещания,
+// если слота нет или под него не подходит ни одно видео, то по стратегии хранилища из всей библиотеки.
+// Вместе с видео возвращается название слота
+func (l *library) pickByStrategy() (string, string) {
+	if slot := l.schedule.Current(time.Now()); slot != nil {
+		if keys := l.slotKeys(slot); len(keys) > 0 {
+			return l.pick(slot.Name, slot.PickStrategy, keys), slot.Name
+		}
+
+		return l.pick("", l.pickStrategy, l.validKeys()), slot.Name
+	}
+
+	return l.pick("", l.pickStrategy, l.validKeys()), ""
+}
+
+func (l *library) pick(cursor string, strategy config.PickStrategy, keys []string) string {
+	// В объединенной библиотеке сначала выбирается источник по весу, затем видео из него,
+	// если стратегия не задана слотом, то по стратегии источника
+	if len(l.sources) > 0 {
+		name := l.pickSource(keys)
+		keys = slices.DeleteFunc(slices.Clone(keys), func(key string) bool {
+			return sourceName(key) != name
+		})
+		cursor = cursor + config.SourceNameSeparator + name
+
+		if len(strategy) == 0 {
+			strategy = l.sources[name].pickStrategy
+		}
+	}
+
+	switch strategy {
+	case config.PickStrategyRandom:
+		return getRandomKey(keys)
+	case config.PickStrategySequential:
+		key := getKeyAfter(keys, l.lastPicked[cursor])
+		l.lastPicked[cursor] = key
+		return key
+	default:
+		return ""
+	}
+}
+
+// slotKeys список видео для слота, для плейлиста в порядке плейлиста, иначе в порядке библиотеки
+func (l *library) slotKeys(slot *schedule.Slot) []string {
+	validKeys := l.validKeys()
+	if len(slot.Playlist) > 0 {
+		keys := make([]string, 0, len(slot.Playlist))
+		for _, key := range slot.Playlist {
+			if slices.Contains(validKeys, key) {
+				keys = append(keys, key)
+			}
+		}
+
+		return keys
+	}
+
+	keys := make([]string, 0, len(validKeys))
+	for _, file := range l.files {
+		if !file.Excluded && slot.Matches(file.Key, file.Meta.Tags) {
+			keys = append(keys, file.Key)
+		}
+	}
+
+	return keys
+}
+
+// pickSource выбирает источник с вероятностью, пропорциональной весу, среди источников, у которых есть видео из keys
+func (l *library) pickSource(keys []string) string {
+	names := []string{}
+	total := 0
+	for _, key := range keys {
+		name := sourceName(key)
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+			total += l.sources[name].weight
+		}
+	}
+
+	if total <= 0 {
+		return getRandomKey(names)
+	}
+
+	return l.weightedSource(names, rand.Intn(total))
+}
+
+// weightedSource источник, на отрезок веса которого попадает n от 0 до суммы весов names
+func (l *library) weightedSource(names []string, n int) string {
+	for _, name := range names {
+		n -= l.sources[name].weight
+		if n < 0 {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// sourceName имя источника из ключа видео объединенной библиотеки
+func sourceName(key string) string {
+	name, _, _ := strings.Cut(key, config.SourceNameSeparator)
+
+	return name
+}
+
+func getRandomKey(keys []string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	if len(keys) == 1 {
+		return keys[0]
+	}
+
+	return keys[rand.Intn(len(keys))]
+}
+
+// getKeyAfter возвращает видео, следующее за last, по кругу; если last нет в списке, то первое
+func getKeyAfter(keys []string, last string) string {
+	if len(keys) == 0 {
+		return ""
+	}
+
+	index := slices.Index(keys, last)
+
+	return keys[(index+1)%len(keys)]
+}
+
+func (l *library) validKeys() []string {
+	keys := make([]string, 0, len(l.files))
+	for _, file := range l.files {
+		if !file.Excluded {
+			keys = append(keys, file.Key)
+		}
+	}
+
+	return keys
+}
+
+// PutVideo по умолчанию не поддерживается, хранилища, в которые можно загружать видео, переопределяют его
+func (l *library) PutVideo(_ context.Context, _ string, _ io.ReadSeeker) error {
+	return ErrReadOnly
+}
+
+func (l *library) DeleteVideo(_ context.Context, _ string) error {
+	return ErrReadOnly
+}
+
+func (l *library) RenameVideo(_ context.Context, _ string, _ string) error {
+	return ErrReadOnly
+}
+
+func (l *library) SetVideoMeta(_ context.Context, _ string, _ VideoMeta) error {
+	return ErrReadOnly
+}
+
+func (l *library) GetQueue() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return slices.Clone(l.queue)
+}
+
+func (l *library) AddToQueue(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if slices.Contains(l.validKeys(), key) {
+		l.queue = append(l.queue, key)
+	}
+}
+
+// SetQueue заменяет очередь целиком, например после изменения порядка видео
+func (l *library) SetQueue(keys []string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	validKeys := l.validKeys()
+	for _, key := range keys {
+		if !slices.Contains(validKeys, key) {
+			return fmt.Errorf("%w: %s", ErrUnknownVideo, key)
+		}
+	}
+
+	l.queue = slices.Clone(keys)
+
+	return nil
+}
+
+func (l *library) GetFilesList() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.validKeys()
+}
+
+func (l *library) GetFilesInfo() []FileInfo {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return slices.Clone(l.files)
+}
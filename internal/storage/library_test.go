@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+)
+
+func newTestLibrary(keys ...string) *library {
+	l := newLibrary(config.PickStrategySequential, nil, nil)
+	files := make([]FileInfo, 0, len(keys))
+	for _, key := range keys {
+		files = append(files, FileInfo{Key: key})
+	}
+	l.setFiles(files)
+
+	return &l
+}
+
+func TestPickNextVideoSkipsMissingQueuedVideos(t *testing.T) {
+	l := newTestLibrary("a.ts", "b.ts", "c.ts")
+	if err := l.SetQueue([]string{"a.ts", "b.ts"}); err != nil {
+		t.Fatalf("SetQueue: %v", err)
+	}
+
+	// Видео пропало из списка, но еще стоит в очереди
+	l.files = slices.DeleteFunc(l.files, func(file FileInfo) bool { return file.Key == "a.ts" })
+
+	file, err := l.PickNextVideo()
+	if err != nil {
+		t.Fatalf("PickNextVideo: %v", err)
+	}
+	if file.Key != "b.ts" {
+		t.Fatalf("PickNextVideo() = %s, want b.ts", file.Key)
+	}
+
+	// Очередь пуста, видео выбирается по стратегии
+	if _, err := l.PickNextVideo(); err != nil {
+		t.Fatalf("PickNextVideo with empty queue: %v", err)
+	}
+}
+
+func TestQueueFollowsDeleteAndRename(t *testing.T) {
+	l := newTestLibrary("a.ts", "b.ts", "c.ts")
+	if err := l.SetQueue([]string{"a.ts", "b.ts", "c.ts"}); err != nil {
+		t.Fatalf("SetQueue: %v", err)
+	}
+
+	l.renameQueued("a.ts", "")
+	l.renameQueued("b.ts", "d.ts")
+	if queue := l.GetQueue(); !slices.Equal(queue, []string{"d.ts", "c.ts"}) {
+		t.Fatalf("queue = %v, want [d.ts c.ts]", queue)
+	}
+
+	// Обновленный список без c.ts убирает его из очереди
+	l.setFiles([]FileInfo{{Key: "b.ts"}, {Key: "d.ts"}})
+	if queue := l.GetQueue(); !slices.Equal(queue, []string{"d.ts"}) {
+		t.Fatalf("queue = %v, want [d.ts]", queue)
+	}
+}
+
+func TestPickByStrategyUsesCurrentSlot(t *testing.T) {
+	files := []FileInfo{
+		{Key: "intro.mp4"},
+		{Key: "morning/a.mp4"},
+		{Key: "news.mp4", Meta: VideoMeta{Tags: []string{"news"}}},
+		{Key: "morning/b.mp4"},
+		{Key: "evening/c.mp4", Meta: VideoMeta{Tags: []string{"news"}}},
+		{Key: "excluded.mp4", Meta: VideoMeta{Tags: []string{"news"}}, Excluded: true},
+	}
+
+	tests := []struct {
+		name string
+		slot config.ConfigScheduleSlot
+		want []string
+	}{
+		{
+			name: "tag",
+			slot: config.ConfigScheduleSlot{Tag: "news"},
+			want: []string{"news.mp4", "evening/c.mp4", "news.mp4"},
+		},
+		{
+			name: "directory",
+			slot: config.ConfigScheduleSlot{Directory: "morning"},
+			want: []string{"morning/a.mp4", "morning/b.mp4", "morning/a.mp4"},
+		},
+		{
+			// Видео плейлиста идут в порядке плейлиста, отсутствующие в библиотеке пропускаются
+			name: "playlist",
+			slot: config.ConfigScheduleSlot{Playlist: []string{"morning/b.mp4", "missing.mp4", "intro.mp4"}},
+			want: []string{"morning/b.mp4", "intro.mp4", "morning/b.mp4"},
+		},
+		{
+			// Под слот не подходит ни одно видео, выбор идет из всей библиотеки
+			name: "no matching videos",
+			slot: config.ConfigScheduleSlot{Tag: "sport"},
+			want: []string{"intro.mp4", "morning/a.mp4", "news.mp4"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Слот без дней с совпадающими началом и концом действует всегда
+			slot := test.slot
+			slot.Name, slot.Start, slot.End = test.name, "00:00", "00:00"
+			slot.PickStrategy = config.PickStrategySequential
+			videoSchedule, err := schedule.New(config.ConfigSchedule{Slots: []config.ConfigScheduleSlot{slot}})
+			if err != nil {
+				t.Fatalf("schedule.New: %v", err)
+			}
+
+			l := newLibrary(config.PickStrategySequential, videoSchedule, nil)
+			l.setFiles(files)
+
+			picked := []string{}
+			for range test.want {
+				file, err := l.PickNextVideo()
+				if err != nil {
+					t.Fatalf("PickNextVideo: %v", err)
+				}
+				picked = append(picked, file.Key)
+			}
+			if !slices.Equal(picked, test.want) {
+				t.Fatalf("picked %v, want %v", picked, test.want)
+			}
+		})
+	}
+}
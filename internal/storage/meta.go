@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// videoMetaFile описывает файл метаданных, который лежит рядом с видеозаписью: video.ts -> video.meta.json
+type videoMetaFile struct {
+	Title    string   `json:"title"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Duration float64  `json:"duration"`
+}
+
+func metaKey(videoKey string) string {
+	return strings.TrimSuffix(videoKey, path.Ext(videoKey)) + metaExtension
+}
+
+func isMetaKey(key string) bool {
+	return strings.HasSuffix(key, metaExtension)
+}
+
+func parseVideoMeta(key string, data []byte) (VideoMeta, error) {
+	var file videoMetaFile
+	err := json.Unmarshal(data, &file)
+	if err != nil {
+		return VideoMeta{Filename: key}, fmt.Errorf("parseVideoMeta.Unmarshal: %w", err)
+	}
+
+	return VideoMeta{
+		Filename: key,
+		Title:    file.Title,
+		Category: file.Category,
+		Tags:     file.Tags,
+		Duration: time.Duration(file.Duration * float64(time.Second)),
+	}, nil
+}
+
+func marshalVideoMeta(meta VideoMeta) ([]byte, error) {
+	data, err := json.MarshalIndent(videoMetaFile{
+		Title:    meta.Title,
+		Category: meta.Category,
+		Tags:     meta.Tags,
+		Duration: meta.Duration.Seconds(),
+	}, "", "    ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalVideoMeta.Marshal: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/rs/zerolog"
+)
+
+// MultiSource источник объединенной библиотеки, его видео выбираются с вероятностью, пропорциональной весу
+type MultiSource struct {
+	Name         string
+	Weight       int
+	PickStrategy config.PickStrategy
+	Storage      Storage
+}
+
+type MultiStorageParams struct {
+	Schedule *schedule.Schedule
+	Sources  []MultiSource
+}
+
+// multiStorage объединяет несколько хранилищ в одну библиотеку, ключ видео в ней состоит из имени источника
+// и ключа в источнике через двоеточие, например archive:vods/1.ts. Очередь, сетка вещания и выбор видео работают
+// с объединенной библиотекой, а источники используются только для получения списка видео и чтения
+type multiStorage struct {
+	library
+
+	sources map[string]Storage
+	names   []string
+}
+
+func NewMultiStorage(ctx context.Context, params MultiStorageParams) (Storage, error) {
+	st := &multiStorage{
+		// Стратегия не задается, видео выбираются по стратегии источника
+		library: newLibrary("", params.Schedule, nil),
+		sources: make(map[string]Storage, len(params.Sources)),
+	}
+
+	st.library.sources = make(map[string]librarySource, len(params.Sources))
+	for _, source := range params.Sources {
+		st.sources[source.Name] = source.Storage
+		st.names = append(st.names, source.Name)
+		st.library.sources[source.Name] = librarySource{
+			weight:       source.Weight,
+			pickStrategy: source.PickStrategy,
+		}
+	}
+
+	st.collect(ctx)
+
+	return st, nil
+}
+
+func (s *multiStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
+}
+
+func (s *multiStorage) OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	source, sourceKey, err := s.split(key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("MultiStorage.OpenVideo: %w", err)
+	}
+
+	return source.OpenVideo(ctx, sourceKey)
+}
+
+func (s *multiStorage) PutVideo(ctx context.Context, key string, video io.ReadSeeker) error {
+	source, sourceKey, err := s.split(key)
+	if err != nil {
+		return fmt.Errorf("MultiStorage.PutVideo: %w", err)
+	}
+
+	return source.PutVideo(ctx, sourceKey, video)
+}
+
+func (s *multiStorage) DeleteVideo(ctx context.Context, key string) error {
+	source, sourceKey, err := s.split(key)
+	if err != nil {
+		return fmt.Errorf("MultiStorage.DeleteVideo: %w", err)
+	}
+
+	err = source.DeleteVideo(ctx, sourceKey)
+	if err != nil {
+		return err
+	}
+
+	s.renameQueued(key, "")
+
+	return nil
+}
+
+// RenameVideo переименовывает видео внутри источника, перенести видео в другой источник нельзя
+func (s *multiStorage) RenameVideo(ctx context.Context, key string, newKey string) error {
+	source, sourceKey, err := s.split(key)
+	if err != nil {
+		return fmt.Errorf("MultiStorage.RenameVideo: %w", err)
+	}
+
+	newSource, newSourceKey, err := s.split(newKey)
+	if err != nil {
+		return fmt.Errorf("MultiStorage.RenameVideo: %w", err)
+	}
+	if newSource != source {
+		return errors.New("MultiStorage.RenameVideo: video can not be moved to another source")
+	}
+
+	err = source.RenameVideo(ctx, sourceKey, newSourceKey)
+	if err != nil {
+		return err
+	}
+
+	s.renameQueued(key, newKey)
+
+	return nil
+}
+
+func (s *multiStorage) SetVideoMeta(ctx context.Context, key string, meta VideoMeta) error {
+	source, sourceKey, err := s.split(key)
+	if err != nil {
+		return fmt.Errorf("MultiStorage.SetVideoMeta: %w", err)
+	}
+
+	return source.SetVideoMeta(ctx, sourceKey, meta)
+}
+
+// UpdateFilesList обновляет списки видео всех источников, при ошибке в одном из них остальные все равно обновляются
+func (s *multiStorage) UpdateFilesList(ctx context.Context) error {
+	var firstErr error
+	for _, name := range s.names {
+		err := s.sources[name].UpdateFilesList(ctx)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("MultiStorage.UpdateFilesList.%s: %w", name, err)
+		}
+	}
+
+	s.collect(ctx)
+
+	return firstErr
+}
+
+func (s *multiStorage) Ping(ctx context.Context) error {
+	for _, name := range s.names {
+		if err := s.sources[name].Ping(ctx); err != nil {
+			return fmt.Errorf("MultiStorage.Ping.%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// collect собирает списки видео источников в общий список, добавляя к ключам имя источника
+func (s *multiStorage) collect(ctx context.Context) {
+	files := []FileInfo{}
+	for _, name := range s.names {
+		for _, file := range s.sources[name].GetFilesInfo() {
+			file.Key = name + config.SourceNameSeparator + file.Key
+			file.Meta.Filename = file.Key
+			files = append(files, file)
+		}
+	}
+
+	s.setFiles(files)
+
+	zerolog.Ctx(ctx).Info().
+		Strs("sources", s.names).
+		Int("files", len(files)).
+		Msg("Sources merged")
+}
+
+func (s *multiStorage) split(key string) (Storage, string, error) {
+	name, sourceKey, ok := strings.Cut(key, config.SourceNameSeparator)
+	source, known := s.sources[name]
+	if !ok || !known {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownVideo, key)
+	}
+
+	return source, sourceKey, nil
+}
+
+// watching включено ли отслеживание изменений хотя бы в одном источнике
+func (s *multiStorage) watching() bool {
+	for _, source := range s.sources {
+		if watcher, ok := source.(watchable); ok && watcher.watching() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// watch следит за изменениями в источниках и после каждого изменения заново собирает общий список
+func (s *multiStorage) watch(ctx context.Context, changed func()) {
+	var wg sync.WaitGroup
+	for _, name := range s.names {
+		watcher, ok := s.sources[name].(watchable)
+		if !ok || !watcher.watching() {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			watcher.watch(ctx, func() {
+				s.collect(ctx)
+				changed()
+			})
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+func TestWeightedSourceDistribution(t *testing.T) {
+	l := newLibrary("", nil, nil)
+	l.sources = map[string]librarySource{
+		"archive": {weight: 3},
+		"clips":   {weight: 1},
+		"empty":   {weight: 5},
+	}
+
+	names := []string{"archive", "clips"}
+	counts := map[string]int{}
+	// Каждое значение от 0 до суммы весов выпадает с одинаковой вероятностью
+	for n := range 4 {
+		counts[l.weightedSource(names, n)]++
+	}
+
+	if counts["archive"] != 3 || counts["clips"] != 1 {
+		t.Fatalf("sources = %v, want archive 3 and clips 1", counts)
+	}
+
+	// Источник без видео из keys не участвует в выборе
+	for range 100 {
+		if name := l.pickSource([]string{"archive:1.ts", "clips:2.ts"}); name == "empty" {
+			t.Fatal("picked source without videos")
+		}
+	}
+}
+
+func TestMultiStorageKeys(t *testing.T) {
+	archive := newStubStorage(FileInfo{Key: "vods/12:30.ts", Size: 1})
+	clips := newStubStorage(FileInfo{Key: "best.ts", Size: 1})
+
+	st, err := NewMultiStorage(context.Background(), MultiStorageParams{
+		Sources: []MultiSource{
+			{Name: "archive", Weight: 1, PickStrategy: config.PickStrategySequential, Storage: archive},
+			{Name: "clips", Weight: 1, PickStrategy: config.PickStrategySequential, Storage: clips},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewMultiStorage: %v", err)
+	}
+
+	files := st.GetFilesList()
+	if !slices.Equal(files, []string{"archive:vods/12:30.ts", "clips:best.ts"}) {
+		t.Fatalf("GetFilesList() = %v", files)
+	}
+
+	// Ключ делится по первому двоеточию, двоеточия в ключе источника сохраняются
+	video, _, err := st.OpenVideo(context.Background(), "archive:vods/12:30.ts")
+	if err != nil {
+		t.Fatalf("OpenVideo: %v", err)
+	}
+	video.Close()
+	if archive.lastKey != "vods/12:30.ts" {
+		t.Fatalf("archive opened %q, want vods/12:30.ts", archive.lastKey)
+	}
+
+	for _, key := range []string{"unknown:best.ts", "best.ts"} {
+		if _, _, err := st.OpenVideo(context.Background(), key); err == nil {
+			t.Fatalf("OpenVideo(%q) succeeded for key without known source", key)
+		}
+	}
+}
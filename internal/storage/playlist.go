@@ -0,0 +1,249 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/rs/zerolog"
+)
+
+var errS3NotConfigured = errors.New("s3 is not configured")
+
+type PlaylistStorageParams struct {
+	PickStrategy config.PickStrategy
+	Schedule     *schedule.Schedule
+	// Playlist путь до файла плейлиста на диске, s3://bucket/key или HTTP адрес
+	Playlist string
+	// Extensions допустимые расширения видео, по умолчанию .ts
+	Extensions []string
+
+	// Настройки s3 нужны, если плейлист или его записи лежат в бакете
+	S3Endpoint          string
+	S3CredentialsID     string
+	S3CredentialsSecret string
+	S3Region            string
+}
+
+// playlistStorage воспроизводит видео из файла плейлиста, записи которого могут лежать на диске, в s3 или по HTTP
+type playlistStorage struct {
+	library
+
+	playlist   string
+	s3Service  *s3.S3
+	httpClient *http.Client
+}
+
+func NewPlaylistStorage(ctx context.Context, params PlaylistStorageParams) (Storage, error) {
+	st := &playlistStorage{
+		library:    newLibrary(params.PickStrategy, params.Schedule, params.Extensions),
+		playlist:   params.Playlist,
+		httpClient: newStreamingClient(),
+	}
+
+	if len(params.S3Endpoint) > 0 {
+		s3Service, err := newS3Service(params.S3Endpoint, params.S3CredentialsID, params.S3CredentialsSecret, params.S3Region)
+		if err != nil {
+			return nil, fmt.Errorf("NewPlaylistStorage.NewSession: %w", err)
+		}
+		st.s3Service = s3Service
+	}
+
+	err := st.UpdateFilesList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PlaylistStorage.UpdateFilesList: %w", err)
+	}
+
+	return st, nil
+}
+
+func (s *playlistStorage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
+}
+
+func (s *playlistStorage) OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	video, size, err := s.open(ctx, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("PlaylistStorage.OpenVideo: %w", err)
+	}
+
+	return video, size, nil
+}
+
+// UpdateFilesList перечитывает плейлист, записи воспроизводятся в порядке плейлиста
+func (s *playlistStorage) UpdateFilesList(ctx context.Context) error {
+	logger := zerolog.Ctx(ctx)
+
+	playlist, _, err := s.open(ctx, s.playlist)
+	if err != nil {
+		return fmt.Errorf("PlaylistStorage.UpdateFilesList.open: %w", err)
+	}
+	defer playlist.Close()
+
+	data, err := io.ReadAll(playlist)
+	if err != nil {
+		return fmt.Errorf("PlaylistStorage.UpdateFilesList.ReadAll: %w", err)
+	}
+
+	entries, err := parsePlaylist(data)
+	if err != nil {
+		return fmt.Errorf("PlaylistStorage.UpdateFilesList: %w", err)
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	videoList := make([]string, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		key := resolveLocation(s.playlist, entry.Location)
+		if _, ok := seen[key]; ok {
+			logger.Warn().Str("file", key).Msg("Duplicate playlist entry skipped")
+			continue
+		}
+		seen[key] = struct{}{}
+
+		file := FileInfo{
+			Key: key,
+			Meta: VideoMeta{
+				Filename: key,
+				Title:    entry.Title,
+				Category: entry.Category,
+				Tags:     entry.Tags,
+				Duration: entry.Duration,
+			},
+		}
+
+		if !s.isVideo(key) {
+			file.Excluded = true
+			file.Reason = "unsupported extension"
+		} else if !isRemoteLocation(key) {
+			// Размер удаленных записей станет известен только при воспроизведении
+			stat, err := os.Stat(key)
+			if err != nil {
+				file.Excluded = true
+				file.Reason = "file not found"
+			} else {
+				file.Size = stat.Size()
+				file.ModTime = stat.ModTime()
+			}
+		}
+
+		if !file.Excluded {
+			videoList = append(videoList, key)
+		}
+
+		files = append(files, file)
+	}
+
+	s.setFiles(files)
+
+	logger.Info().
+		Strs("files", videoList).
+		Msg("Files list updated")
+
+	return nil
+}
+
+// Ping проверяет, что файл плейлиста доступен
+func (s *playlistStorage) Ping(ctx context.Context) error {
+	playlist, _, err := s.open(ctx, s.playlist)
+	if err != nil {
+		return fmt.Errorf("PlaylistStorage.Ping: %w", err)
+	}
+
+	return playlist.Close()
+}
+
+// open открывает файл на диске, объект в s3 или HTTP адрес и возвращает его размер, если он известен
+func (s *playlistStorage) open(ctx context.Context, location string) (io.ReadCloser, int64, error) {
+	if !isRemoteLocation(location) {
+		return openLocalFile(location)
+	}
+
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return nil, 0, fmt.Errorf("PlaylistStorage.open.Parse: %w", err)
+	}
+
+	switch locationURL.Scheme {
+	case "s3":
+		if s.s3Service == nil {
+			return nil, 0, errS3NotConfigured
+		}
+
+		return openS3(ctx, s.s3Service, locationURL.Host, strings.TrimPrefix(locationURL.Path, "/"))
+	default:
+		return openHTTP(ctx, s.httpClient, location)
+	}
+}
+
+func openLocalFile(location string) (io.ReadCloser, int64, error) {
+	f, err := os.Open(location)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, err
+	}
+
+	return f, stat.Size(), nil
+}
+
+func isRemoteLocation(location string) bool {
+	return strings.HasPrefix(location, "s3://") ||
+		strings.HasPrefix(location, "http://") ||
+		strings.HasPrefix(location, "https://")
+}
+
+// resolveLocation превращает относительный путь записи в путь относительно расположения плейлиста
+func resolveLocation(playlist string, location string) string {
+	if isRemoteLocation(location) {
+		return location
+	}
+
+	if isRemoteLocation(playlist) {
+		base, err := url.Parse(playlist)
+		if err != nil {
+			return location
+		}
+
+		ref, err := url.Parse(location)
+		if err != nil {
+			return location
+		}
+
+		return base.ResolveReference(ref).String()
+	}
+
+	if filepath.IsAbs(location) {
+		return location
+	}
+
+	return filepath.Join(filepath.Dir(playlist), filepath.FromSlash(location))
+}
+
+// locationPath возвращает путь без параметров запроса, чтобы проверить расширение файла
+func locationPath(location string) string {
+	if !isRemoteLocation(location) {
+		return location
+	}
+
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	return path.Clean(locationURL.Path)
+}
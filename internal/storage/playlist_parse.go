@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playlistEntry запись плейлиста: путь на диске, s3://bucket/key или HTTP адрес видео
+type playlistEntry struct {
+	Location string
+	Title    string
+	Category string
+	Tags     []string
+	Duration time.Duration
+}
+
+// playlistFileEntry запись JSON плейлиста
+type playlistFileEntry struct {
+	Path     string   `json:"path"`
+	Title    string   `json:"title"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Duration float64  `json:"duration"`
+}
+
+// parsePlaylist разбирает плейлист в формате JSON (массив записей или объект с полем entries) или M3U/M3U8
+func parsePlaylist(data []byte) ([]playlistEntry, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		return parseJSONPlaylist(trimmed)
+	}
+
+	return parseM3UPlaylist(data)
+}
+
+func parseJSONPlaylist(data []byte) ([]playlistEntry, error) {
+	var fileEntries []playlistFileEntry
+	if data[0] == '{' {
+		var file struct {
+			Entries []playlistFileEntry `json:"entries"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("parseJSONPlaylist.Unmarshal: %w", err)
+		}
+		fileEntries = file.Entries
+	} else if err := json.Unmarshal(data, &fileEntries); err != nil {
+		return nil, fmt.Errorf("parseJSONPlaylist.Unmarshal: %w", err)
+	}
+
+	entries := make([]playlistEntry, 0, len(fileEntries))
+	for i, fileEntry := range fileEntries {
+		if len(fileEntry.Path) == 0 {
+			return nil, fmt.Errorf("parseJSONPlaylist: entry %d has no path", i)
+		}
+
+		entries = append(entries, playlistEntry{
+			Location: fileEntry.Path,
+			Title:    fileEntry.Title,
+			Category: fileEntry.Category,
+			Tags:     fileEntry.Tags,
+			Duration: time.Duration(fileEntry.Duration * float64(time.Second)),
+		})
+	}
+
+	return entries, nil
+}
+
+// parseM3UPlaylist разбирает M3U, длительность и название берутся из строки #EXTINF:<длительность>,<название>
+// перед записью, остальные директивы пропускаются
+func parseM3UPlaylist(data []byte) ([]playlistEntry, error) {
+	entries := []playlistEntry{}
+	var pending playlistEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			// После длительности могут идти атрибуты вида tvg-name="...", они не используются
+			durationField, _, _ := strings.Cut(strings.TrimSpace(info), " ")
+			duration, err := strconv.ParseFloat(durationField, 64)
+			if err == nil && duration > 0 {
+				pending.Duration = time.Duration(duration * float64(time.Second))
+			}
+			pending.Title = strings.TrimSpace(title)
+		case strings.HasPrefix(line, "#"):
+			continue
+		default:
+			pending.Location = line
+			entries = append(entries, pending)
+			pending = playlistEntry{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parseM3UPlaylist.Scan: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+func TestParseM3UPlaylist(t *testing.T) {
+	data := "#EXTM3U\r\n" +
+		"\r\n" +
+		"#EXTINF:125.5,Первый выпуск\r\n" +
+		"first.ts\r\n" +
+		"# обычный комментарий\n" +
+		`#EXTINF:-1 tvg-name="live" group-title="news",  Прямой эфир  ` + "\n" +
+		"#EXTVLCOPT:network-caching=1000\n" +
+		"https://cdn.example.com/live.ts\n" +
+		"s3://bucket/without-info.ts\n" +
+		"#EXTINF:bad,Без длительности\n" +
+		"/var/videos/last.ts\n"
+
+	entries, err := parsePlaylist([]byte(data))
+	if err != nil {
+		t.Fatalf("parsePlaylist: %v", err)
+	}
+
+	want := []playlistEntry{
+		{Location: "first.ts", Title: "Первый выпуск", Duration: 125500 * time.Millisecond},
+		// Длительность -1 означает, что она неизвестна, атрибуты перед названием пропускаются
+		{Location: "https://cdn.example.com/live.ts", Title: "Прямой эфир"},
+		// Описание #EXTINF относится только к следующей записи
+		{Location: "s3://bucket/without-info.ts"},
+		{Location: "/var/videos/last.ts", Title: "Без длительности"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("parsePlaylist() = %+v, want %+v", entries, want)
+	}
+}
+
+func TestParseJSONPlaylist(t *testing.T) {
+	want := []playlistEntry{
+		{Location: "first.ts", Title: "Первый выпуск", Category: "Игры", Tags: []string{"news"}, Duration: 90500 * time.Millisecond},
+		{Location: "s3://bucket/second.ts"},
+	}
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{
+			name: "array",
+			data: `[
+				{"path": "first.ts", "title": "Первый выпуск", "category": "Игры", "tags": ["news"], "duration": 90.5},
+				{"path": "s3://bucket/second.ts"}
+			]`,
+		},
+		{
+			name: "object with entries",
+			data: `
+				{"entries": [
+					{"path": "first.ts", "title": "Первый выпуск", "category": "Игры", "tags": ["news"], "duration": 90.5},
+					{"path": "s3://bucket/second.ts"}
+				]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries, err := parsePlaylist([]byte(test.data))
+			if err != nil {
+				t.Fatalf("parsePlaylist: %v", err)
+			}
+			if !reflect.DeepEqual(entries, want) {
+				t.Fatalf("parsePlaylist() = %+v, want %+v", entries, want)
+			}
+		})
+	}
+}
+
+func TestParsePlaylistMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "broken array", data: `[{"path": "first.ts"}`},
+		{name: "broken object", data: `{"entries": [}`},
+		{name: "entry without path", data: `[{"path": "first.ts"}, {"title": "Без пути"}]`},
+		{name: "wrong field type", data: `[{"path": "first.ts", "duration": "long"}]`},
+		{name: "entries is not array", data: `{"entries": "first.ts"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if entries, err := parsePlaylist([]byte(test.data)); err == nil {
+				t.Fatalf("parsePlaylist() = %+v, want error", entries)
+			}
+		})
+	}
+
+	// В M3U нет обязательной структуры, строки без записей дают пустой плейлист
+	entries, err := parsePlaylist([]byte("#EXTM3U\n#EXTINF:10,Без записи\n"))
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("parsePlaylist() = %+v, %v, want empty playlist", entries, err)
+	}
+}
+
+func TestResolveLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		playlist string
+		location string
+		want     string
+	}{
+		{name: "local relative", playlist: "/var/playlists/week.m3u", location: "clips/a.ts", want: "/var/playlists/clips/a.ts"},
+		{name: "local parent", playlist: "/var/playlists/week.m3u", location: "../videos/a.ts", want: "/var/videos/a.ts"},
+		{name: "local absolute", playlist: "/var/playlists/week.m3u", location: "/srv/a.ts", want: "/srv/a.ts"},
+		{name: "local playlist remote entry", playlist: "/var/playlists/week.m3u", location: "s3://bucket/a.ts", want: "s3://bucket/a.ts"},
+		{name: "s3 relative", playlist: "s3://bucket/lists/week.m3u", location: "clips/a.ts", want: "s3://bucket/lists/clips/a.ts"},
+		{name: "s3 rooted", playlist: "s3://bucket/lists/week.m3u", location: "/a.ts", want: "s3://bucket/a.ts"},
+		{name: "http relative", playlist: "https://cdn.example.com/lists/week.m3u?token=1", location: "a.ts", want: "https://cdn.example.com/lists/a.ts"},
+		{name: "http playlist other entry", playlist: "https://cdn.example.com/week.m3u", location: "http://other.example.com/a.ts", want: "http://other.example.com/a.ts"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := resolveLocation(test.playlist, test.location); got != test.want {
+				t.Fatalf("resolveLocation(%s, %s) = %s, want %s", test.playlist, test.location, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlaylistStorageLocalPlaylist(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.ts", "b.ts", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	playlist := filepath.Join(dir, "week.m3u")
+	data := "#EXTM3U\n" +
+		"#EXTINF:60,Первое\n" +
+		"a.ts\n" +
+		"https://cdn.example.com/remote.ts?token=1\n" +
+		"s3://bucket/remote.ts\n" +
+		// Та же запись другим путем считается повтором
+		"./a.ts\n" +
+		filepath.Join(dir, "b.ts") + "\n" +
+		"missing.ts\n" +
+		"notes.txt\n"
+	if err := os.WriteFile(playlist, []byte(data), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	st, err := NewPlaylistStorage(context.Background(), PlaylistStorageParams{
+		PickStrategy: config.PickStrategySequential,
+		Playlist:     playlist,
+	})
+	if err != nil {
+		t.Fatalf("NewPlaylistStorage: %v", err)
+	}
+
+	// Записи идут в порядке плейлиста, удаленные не проверяются до воспроизведения
+	want := []string{
+		filepath.Join(dir, "a.ts"),
+		"https://cdn.example.com/remote.ts?token=1",
+		"s3://bucket/remote.ts",
+		filepath.Join(dir, "b.ts"),
+	}
+	if files := st.GetFilesList(); !reflect.DeepEqual(files, want) {
+		t.Fatalf("GetFilesList() = %v, want %v", files, want)
+	}
+
+	reasons := map[string]string{}
+	for _, file := range st.GetFilesInfo() {
+		reasons[filepath.Base(file.Key)] = file.Reason
+		if file.Key == want[0] && (file.Meta.Title != "Первое" || file.Meta.Duration != time.Minute || file.Size != 4) {
+			t.Fatalf("first entry = %+v, want title, duration and size", file)
+		}
+	}
+	if reasons["missing.ts"] != "file not found" || reasons["notes.txt"] != "unsupported extension" {
+		t.Fatalf("excluded reasons = %v", reasons)
+	}
+
+	// Для записей в s3 нужны настройки s3
+	if _, _, err := st.OpenVideo(context.Background(), "s3://bucket/remote.ts"); err == nil {
+		t.Fatal("OpenVideo() of s3 entry without s3 settings error = nil, want error")
+	}
+}
+
+func TestPlaylistStorageS3Playlist(t *testing.T) {
+	objects := map[string]string{
+		"/bucket/lists/week.json":  `[{"path": "clips/a.ts", "title": "Первое"}, {"path": "/b.ts"}]`,
+		"/bucket/lists/clips/a.ts": "video a",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := objects[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	st, err := NewPlaylistStorage(context.Background(), PlaylistStorageParams{
+		PickStrategy:        config.PickStrategySequential,
+		Playlist:            "s3://bucket/lists/week.json",
+		S3Endpoint:          server.URL,
+		S3CredentialsID:     "id",
+		S3CredentialsSecret: "secret",
+		S3Region:            "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewPlaylistStorage: %v", err)
+	}
+
+	want := []string{"s3://bucket/lists/clips/a.ts", "s3://bucket/b.ts"}
+	if files := st.GetFilesList(); !reflect.DeepEqual(files, want) {
+		t.Fatalf("GetFilesList() = %v, want %v", files, want)
+	}
+
+	video, _, err := st.OpenVideo(context.Background(), want[0])
+	if err != nil {
+		t.Fatalf("OpenVideo: %v", err)
+	}
+	defer video.Close()
+
+	content, err := io.ReadAll(video)
+	if err != nil || string(content) != "video a" {
+		t.Fatalf("ReadAll() = %q, %v, want video a", content, err)
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	resumeBudget         = 10
+	resumeInitialBackoff = time.Second
+	resumeMaxBackoff     = 30 * time.Second
+	// readStallTimeout сколько может длиться одно чтение, зависшее соединение без ошибки тоже считается обрывом
+	readStallTimeout = 30 * time.Second
+	// responseHeaderTimeout и idleConnTimeout ограничения HTTP клиента для видео по HTTP и из s3
+	responseHeaderTimeout = 30 * time.Second
+	idleConnTimeout       = 90 * time.Second
+)
+
+var errReadStalled = errors.New("read stalled")
+
+// newStreamingClient HTTP клиент для чтения видео. Общий таймаут запроса не задается, иначе длинное видео
+// прервется посреди воспроизведения, зависшее чтение тела отслеживает resumingReader
+func newStreamingClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = responseHeaderTimeout
+	transport.IdleConnTimeout = idleConnTimeout
+
+	return &http.Client{Transport: transport}
+}
+
+// rangeOpener открывает видео с указанного смещения в байтах
+type rangeOpener func(ctx context.Context, offset int64) (io.ReadCloser, error)
+
+// resumingReader читает видео и при сетевой ошибке переоткрывает его с того места, где чтение прервалось.
+// Попытки переоткрыть делаются с экспоненциальной задержкой, после resumeBudget попыток за все время
+// чтения видео ошибка возвращается читающему
+type resumingReader struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	open   rangeOpener
+
+	body   io.ReadCloser
+	offset int64
+	size   int64
+	budget int
+	// stallTimeout через сколько прерывается чтение, которое не вернуло ни данных, ни ошибки
+	stallTimeout time.Duration
+}
+
+// newResumingReader оборачивает уже открытое тело видео, size может быть 0, если размер неизвестен.
+// ctx контекст воспроизведения видео, его отмена прерывает ожидание перед повторной попыткой
+func newResumingReader(ctx context.Context, body io.ReadCloser, size int64, open rangeOpener) *resumingReader {
+	ctx, cancel := context.WithCancel(ctx)
+
+	return &resumingReader{
+		ctx:    ctx,
+		cancel: cancel,
+		open:   open,
+		body:   body,
+		size:   size,
+		budget: resumeBudget,
+
+		stallTimeout: readStallTimeout,
+	}
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	n, err := r.readBody(p)
+	r.offset += int64(n)
+
+	// Если размер известен, то преждевременный конец тоже считаем обрывом соединения
+	if errors.Is(err, io.EOF) && (r.size <= 0 || r.offset >= r.size) {
+		return n, err
+	}
+
+	if err == nil {
+		return n, nil
+	}
+
+	if resumeErr := r.resume(err); resumeErr != nil {
+		return n, resumeErr
+	}
+
+	return n, nil
+}
+
+// readBody читает тело видео, зависшее чтение прерывается закрытием тела, после чего видео переоткрывается
+func (r *resumingReader) readBody(p []byte) (int, error) {
+	body := r.body
+	timer := time.AfterFunc(r.stallTimeout, func() {
+		body.Close()
+	})
+
+	n, err := body.Read(p)
+	if !timer.Stop() {
+		return n, fmt.Errorf("resumingReader: %w for %s", errReadStalled, r.stallTimeout)
+	}
+
+	return n, err
+}
+
+func (r *resumingReader) resume(cause error) error {
+	backoff := resumeInitialBackoff
+	for r.budget > 0 {
+		r.budget--
+
+		select {
+		case <-r.ctx.Done():
+			return fmt.Errorf("resumingReader: %w: %w", r.ctx.Err(), cause)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, resumeMaxBackoff)
+
+		body, err := r.open(r.ctx, r.offset)
+		if errors.Is(err, errFileChanged) {
+			return fmt.Errorf("resumingReader: %w", err)
+		}
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		r.body.Close()
+		r.body = body
+
+		return nil
+	}
+
+	return fmt.Errorf("resumingReader: retry budget spent at offset %d: %w", r.offset, cause)
+}
+
+func (r *resumingReader) Close() error {
+	r.cancel()
+
+	return r.body.Close()
+}
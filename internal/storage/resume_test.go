@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingBody отдает данные, а потом обрывает чтение ошибкой, как оборванное соединение
+type failingBody struct {
+	io.Reader
+}
+
+func (b failingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+func (failingBody) Close() error {
+	return nil
+}
+
+func TestResumingReaderStopsOnContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	opens := 0
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		opens++
+		return nil, errors.New("connection refused")
+	}
+
+	reader := newResumingReader(ctx, failingBody{strings.NewReader("")}, 100, open)
+	defer reader.Close()
+
+	// Отмена воспроизведения во время ожидания перед первой повторной попыткой
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := reader.Read(make([]byte, 10))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Read() error = %v, want %v", err, context.Canceled)
+	}
+
+	if elapsed := time.Since(start); elapsed >= resumeInitialBackoff {
+		t.Fatalf("Read() returned after %s, want before the first retry", elapsed)
+	}
+
+	if opens != 0 {
+		t.Fatalf("video reopened %d times after cancel", opens)
+	}
+}
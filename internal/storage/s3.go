@@ -1,17 +1,21 @@
 package storage
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"slices"
 	"strings"
 	"time"
 
-	"math/rand"
-
 	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/schedule"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
@@ -21,8 +25,15 @@ import (
 type S3StorageParams struct {
 	Bucket        string
 	PickStrategy  config.PickStrategy
+	Schedule      *schedule.Schedule
 	DirectoryPath string
-	Files         []string
+	// Prefixes дополнительные префиксы ключей, из которых берутся видео вместе с DirectoryPath
+	Prefixes []string
+	Files    []string
+	// Extensions допустимые расширения видео, по умолчанию .ts
+	Extensions []string
+	// RelistInterval интервал, с которым перечитывается список файлов, 0 чтобы не перечитывать
+	RelistInterval time.Duration
 
 	Endpoint          string
 	CredentialsID     string
@@ -31,113 +42,299 @@ type S3StorageParams struct {
 }
 
 type s3Storage struct {
+	library
+
 	s3Service *s3.S3
 	bucket    string
 
-	pickStrategy  config.PickStrategy
-	directoryPath string
-	filesList     []string
-	queue         []string
+	prefixes       []string
+	files          []string
+	relistInterval time.Duration
 }
 
 func boolPrt(value bool) *bool {
 	return &value
 }
 
-func NewS3Storage(ctx context.Context, params S3StorageParams) (Storage, error) {
+func newS3Service(endpoint string, credentialsID string, credentialsSecret string, region string) (*s3.S3, error) {
 	sess, err := session.NewSession(&aws.Config{
-		Endpoint:         &params.Endpoint,
-		Credentials:      credentials.NewStaticCredentials(params.CredentialsID, params.CredentialsSecret, ""),
+		Endpoint:         &endpoint,
+		Credentials:      credentials.NewStaticCredentials(credentialsID, credentialsSecret, ""),
 		S3ForcePathStyle: boolPrt(true),
-		Region:           &params.Region,
+		Region:           &region,
+		HTTPClient:       newStreamingClient(),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("NewS3Storage.NewSession: %w", err)
+		return nil, err
 	}
 
-	s3Service := s3.New(sess)
+	return s3.New(sess), nil
+}
+
+func NewS3Storage(ctx context.Context, params S3StorageParams) (Storage, error) {
+	s3Service, err := newS3Service(params.Endpoint, params.CredentialsID, params.CredentialsSecret, params.Region)
+	if err != nil {
+		return nil, fmt.Errorf("NewS3Storage.NewSession: %w", err)
+	}
 
 	st := &s3Storage{
-		s3Service:     s3Service,
-		directoryPath: params.DirectoryPath,
-		pickStrategy:  params.PickStrategy,
-		bucket:        params.Bucket,
-		queue:         []string{},
+		library:        newLibrary(params.PickStrategy, params.Schedule, params.Extensions),
+		s3Service:      s3Service,
+		bucket:         params.Bucket,
+		prefixes:       listPrefixes(params.DirectoryPath, params.Prefixes),
+		files:          params.Files,
+		relistInterval: params.RelistInterval,
 	}
 
-	if len(params.Files) == 0 {
-		err := st.UpdateFilesList(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("S3Storage.UpdateFilesList: %w", err)
-		}
+	err = st.UpdateFilesList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("S3Storage.UpdateFilesList: %w", err)
 	}
 
 	return st, nil
 }
 
-func (s *s3Storage) GetNextVideo() (io.ReadCloser, int64, *VideoMeta) {
-	var key string
+func (s *s3Storage) GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error) {
+	return getNextVideo(ctx, s)
+}
 
-	if len(s.queue) > 0 {
-		key, s.queue = s.queue[0], s.queue[1:]
-	} else {
-		switch s.pickStrategy {
-		case config.PickStrategyRandom:
-			key = s.getRandomVideoKey()
-		default:
-			return nil, 0, nil
+func (s *s3Storage) OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	video, size, err := openS3(ctx, s.s3Service, s.bucket, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("S3Storage.OpenVideo: %w", err)
+	}
+
+	return video, size, nil
+}
+
+// openS3 открывает объект, при обрыве соединения чтение продолжается запросом с Range с того же байта
+func openS3(ctx context.Context, s3Service *s3.S3, bucket string, key string) (io.ReadCloser, int64, error) {
+	res, err := s3Service.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("openS3.GetObject: %w", err)
+	}
+
+	open := func(ctx context.Context, offset int64) (io.ReadCloser, error) {
+		res, err := s3Service.GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-", offset)),
+			// Если объект перезаписали во время воспроизведения, то продолжать с того же места нельзя
+			IfMatch: res.ETag,
+		})
+		// Перезаписанный объект уже никогда не совпадет с ETag, повторять бесполезно
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusPreconditionFailed {
+			return nil, fmt.Errorf("openS3: %w: resume at offset %d", errFileChanged, offset)
 		}
+		if err != nil {
+			return nil, fmt.Errorf("openS3.GetObject: %w", err)
+		}
+
+		return res.Body, nil
 	}
 
-	res, err := s.s3Service.GetObject(&s3.GetObjectInput{
-		Key:    &key,
-		Bucket: &s.bucket,
+	size := aws.Int64Value(res.ContentLength)
+
+	return newResumingReader(ctx, res.Body, size, open), size, nil
+}
+
+func (s *s3Storage) PutVideo(ctx context.Context, key string, video io.ReadSeeker) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	existing, err := s.headObject(ctx, key)
+	if err != nil {
+		return fmt.Errorf("S3Storage.PutVideo: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("S3Storage.PutVideo: %w: %s", ErrVideoExists, key)
+	}
+
+	_, err = s.s3Service.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   video,
 	})
 	if err != nil {
-		return nil, 0, nil
+		return fmt.Errorf("S3Storage.PutVideo.PutObject: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3Storage) DeleteVideo(ctx context.Context, key string) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	// Удаление несуществующего объекта в s3 не считается ошибкой, поэтому файл метаданных удаляется без проверки
+	for _, objectKey := range []string{key, metaKey(key)} {
+		_, err := s.s3Service.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(objectKey),
+		})
+		if err != nil {
+			return fmt.Errorf("S3Storage.DeleteVideo.DeleteObject: %w", err)
+		}
+	}
+
+	s.renameQueued(key, "")
+
+	return nil
+}
+
+// RenameVideo копирует объекты под новым ключом и удаляет старые, в s3 нет переименования
+func (s *s3Storage) RenameVideo(ctx context.Context, key string, newKey string) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
+	}
+
+	existing, err := s.headObject(ctx, newKey)
+	if err != nil {
+		return fmt.Errorf("S3Storage.RenameVideo: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("S3Storage.RenameVideo: %w: %s", ErrVideoExists, newKey)
 	}
 
-	var bodyLength int64 = 0
-	if res.ContentLength != nil {
-		bodyLength = *res.ContentLength
+	err = s.moveObject(ctx, key, newKey)
+	if err != nil {
+		return fmt.Errorf("S3Storage.RenameVideo: %w", err)
 	}
+	s.renameQueued(key, newKey)
 
-	return res.Body, bodyLength, &VideoMeta{
-		Filename: key,
+	meta, err := s.headObject(ctx, metaKey(key))
+	if err != nil {
+		return fmt.Errorf("S3Storage.RenameVideo: %w", err)
+	}
+	if meta != nil {
+		err = s.moveObject(ctx, metaKey(key), metaKey(newKey))
+		if err != nil {
+			return fmt.Errorf("S3Storage.RenameVideo: %w", err)
+		}
 	}
+
+	return nil
+}
+
+func (s *s3Storage) moveObject(ctx context.Context, key string, newKey string) error {
+	// Ключ источника копирования передается в URL-кодировке
+	source := (&url.URL{Path: s.bucket + "/" + key}).EscapedPath()
+	_, err := s.s3Service.CopyObjectWithContext(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucket),
+		Key:        aws.String(newKey),
+		CopySource: aws.String(source),
+	})
+	if err != nil {
+		return fmt.Errorf("moveObject.CopyObject: %w", err)
+	}
+
+	_, err = s.s3Service.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("moveObject.DeleteObject: %w", err)
+	}
+
+	return nil
 }
 
-func (s *s3Storage) getRandomVideoKey() string {
-	if len(s.filesList) == 1 {
-		return s.filesList[0]
+func (s *s3Storage) SetVideoMeta(ctx context.Context, key string, meta VideoMeta) error {
+	if len(s.files) > 0 {
+		return ErrReadOnly
 	}
 
-	source := rand.NewSource(time.Now().Unix())
-	r := rand.New(source)
+	data, err := marshalVideoMeta(meta)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.s3Service.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(metaKey(key)),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("S3Storage.SetVideoMeta.PutObject: %w", err)
+	}
 
-	return s.filesList[r.Intn(len(s.filesList))]
+	return nil
 }
 
 func (s *s3Storage) UpdateFilesList(ctx context.Context) error {
 	logger := zerolog.Ctx(ctx)
 
-	directory := strings.TrimRight(s.directoryPath, "/")
-	list, err := s.s3Service.ListObjectsV2(&s3.ListObjectsV2Input{
-		Bucket: &s.bucket,
-		Prefix: &directory,
-	})
+	var objects []*s3.Object
+	var err error
+	if len(s.files) > 0 {
+		objects, err = s.headFiles(ctx)
+	} else {
+		objects, err = s.listObjects(ctx)
+	}
 	if err != nil {
-		return fmt.Errorf("NewS3Storage.UpdateFilesList.ListObjectsV2: %w", err)
+		return fmt.Errorf("S3Storage.UpdateFilesList: %w", err)
 	}
 
-	videoList := make([]string, 0, len(list.Contents))
-	for _, object := range list.Contents {
-		if strings.HasSuffix(*object.Key, ".ts") {
-			videoList = append(videoList, *object.Key)
+	metaKeys := make(map[string]struct{})
+	for _, object := range objects {
+		if isMetaKey(*object.Key) {
+			metaKeys[*object.Key] = struct{}{}
 		}
 	}
 
-	s.filesList = videoList
+	files := make([]FileInfo, 0, len(objects))
+	videoList := make([]string, 0, len(objects))
+	for _, object := range objects {
+		key := *object.Key
+		if isMetaKey(key) || strings.HasSuffix(key, "/") {
+			continue
+		}
+
+		file := FileInfo{
+			Key:     key,
+			Size:    aws.Int64Value(object.Size),
+			ModTime: aws.TimeValue(object.LastModified),
+			ETag:    strings.Trim(aws.StringValue(object.ETag), `"`),
+			Meta:    VideoMeta{Filename: key},
+		}
+
+		switch {
+		case object.LastModified == nil:
+			// headFiles не заполняет дату изменения, если объекта нет в бакете
+			file.Excluded = true
+			file.Reason = "file not found"
+		case !s.isVideo(key):
+			file.Excluded = true
+			file.Reason = "unsupported extension"
+		default:
+			videoList = append(videoList, key)
+		}
+
+		if _, ok := metaKeys[metaKey(key)]; ok {
+			meta, err := s.readMeta(key)
+			if err != nil {
+				logger.Warn().Err(err).Str("file", key).Msg("Unable to read video metadata")
+			} else {
+				file.Meta = meta
+			}
+		}
+
+		files = append(files, file)
+	}
+
+	// Явный список файлов воспроизводится в указанном порядке, а найденные по префиксам - по дате изменения
+	if len(s.files) == 0 {
+		sortByModTime(files)
+	}
+
+	s.setFiles(files)
 
 	logger.Info().
 		Strs("files", videoList).
@@ -146,16 +343,136 @@ func (s *s3Storage) UpdateFilesList(ctx context.Context) error {
 	return nil
 }
 
-func (s *s3Storage) GetQueue() []string {
-	return s.queue
+// listObjects получает все объекты по всем префиксам, постранично, без ограничения в 1000 объектов
+func (s *s3Storage) listObjects(ctx context.Context) ([]*s3.Object, error) {
+	objects := []*s3.Object{}
+	seen := map[string]struct{}{}
+	for _, prefix := range s.prefixes {
+		err := s.s3Service.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+			Bucket: &s.bucket,
+			Prefix: aws.String(prefix),
+		}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+			for _, object := range page.Contents {
+				// Префиксы могут пересекаться, каждый объект берем один раз
+				if _, ok := seen[*object.Key]; ok {
+					continue
+				}
+				seen[*object.Key] = struct{}{}
+
+				objects = append(objects, object)
+			}
+
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("S3Storage.listObjects.ListObjectsV2Pages: %w", err)
+		}
+	}
+
+	return objects, nil
 }
 
-func (s *s3Storage) AddToQueue(key string) {
-	if slices.Contains(s.filesList, key) {
-		s.queue = append(s.queue, key)
+// headFiles получает размеры файлов из явного списка и ищет для них файлы метаданных,
+// для отсутствующих в бакете файлов дата изменения не заполняется
+func (s *s3Storage) headFiles(ctx context.Context) ([]*s3.Object, error) {
+	objects := make([]*s3.Object, 0, len(s.files)*2)
+	for _, key := range s.files {
+		object := &s3.Object{Key: aws.String(key)}
+		head, err := s.headObject(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if head != nil {
+			object.Size = head.ContentLength
+			object.LastModified = head.LastModified
+			object.ETag = head.ETag
+		}
+		objects = append(objects, object)
+
+		meta, err := s.headObject(ctx, metaKey(key))
+		if err != nil {
+			return nil, err
+		}
+		if meta != nil {
+			objects = append(objects, &s3.Object{Key: aws.String(metaKey(key))})
+		}
 	}
+
+	return objects, nil
+}
+
+// headObject возвращает nil без ошибки, если объекта нет в бакете
+func (s *s3Storage) headObject(ctx context.Context, key string) (*s3.HeadObjectOutput, error) {
+	head, err := s.s3Service.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var requestErr awserr.RequestFailure
+		if errors.As(err, &requestErr) && requestErr.StatusCode() == http.StatusNotFound {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("S3Storage.headObject.HeadObject: %w", err)
+	}
+
+	return head, nil
+}
+
+// listPrefixes собирает префиксы для поиска видео, без префиксов ищем по всему бакету
+func listPrefixes(directoryPath string, prefixes []string) []string {
+	result := []string{}
+	if len(directoryPath) > 0 {
+		result = append(result, strings.TrimLeft(strings.TrimRight(directoryPath, "/"), "/"))
+	}
+
+	for _, prefix := range prefixes {
+		result = append(result, strings.TrimLeft(prefix, "/"))
+	}
+
+	if len(result) == 0 {
+		return []string{""}
+	}
+
+	return slices.Compact(result)
+}
+
+// Ping проверяет доступность бакета
+func (s *s3Storage) Ping(ctx context.Context) error {
+	_, err := s.s3Service.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: &s.bucket,
+	})
+	if err != nil {
+		return fmt.Errorf("S3Storage.Ping.HeadBucket: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3Storage) readMeta(videoKey string) (VideoMeta, error) {
+	key := metaKey(videoKey)
+	res, err := s.s3Service.GetObject(&s3.GetObjectInput{
+		Key:    &key,
+		Bucket: &s.bucket,
+	})
+	if err != nil {
+		return VideoMeta{Filename: videoKey}, fmt.Errorf("S3Storage.readMeta.GetObject: %w", err)
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return VideoMeta{Filename: videoKey}, fmt.Errorf("S3Storage.readMeta.ReadAll: %w", err)
+	}
+
+	return parseVideoMeta(videoKey, data)
+}
+
+func (s *s3Storage) watching() bool {
+	return s.relistInterval > 0
 }
 
-func (s *s3Storage) GetFilesList() []string {
-	return s.filesList
+// watch перечитывает список файлов в бакете с интервалом
+func (s *s3Storage) watch(ctx context.Context, changed func()) {
+	relist(ctx, s, s.relistInterval, changed)
 }
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestOpenS3FailsWhenObjectOverwritten(t *testing.T) {
+	content := make([]byte, 64*1024)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		// Объект перезаписан, ETag больше не совпадает с If-Match
+		if r.Header.Get("If-Match") != `"v1"` || r.Header.Get("Range") == "" {
+			t.Errorf("resume request without If-Match or Range: %v", r.Header)
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?>`+
+			`<Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+	}))
+	defer server.Close()
+
+	s3Service, err := newS3Service(server.URL, "id", "secret", "us-east-1")
+	if err != nil {
+		t.Fatalf("newS3Service: %v", err)
+	}
+
+	video, size, err := openS3(context.Background(), s3Service, "bucket", "video.ts")
+	if err != nil {
+		t.Fatalf("openS3: %v", err)
+	}
+	defer video.Close()
+
+	if size != int64(len(content)) {
+		t.Fatalf("size = %d, want %d", size, len(content))
+	}
+
+	_, err = io.ReadAll(video)
+	if !errors.Is(err, errFileChanged) {
+		t.Fatalf("ReadAll() error = %v, want %v", err, errFileChanged)
+	}
+
+	// На 412 повторять бесполезно, бюджет попыток не тратится
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+}
+
+func TestS3PutVideoKeepsExistingObject(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("unexpected %s request, existing object must not be replaced", r.Method)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "1")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	s3Service, err := newS3Service(server.URL, "id", "secret", "us-east-1")
+	if err != nil {
+		t.Fatalf("newS3Service: %v", err)
+	}
+
+	st := &s3Storage{s3Service: s3Service, bucket: "bucket"}
+	err = st.PutVideo(context.Background(), "video.ts", strings.NewReader("new"))
+	if !errors.Is(err, ErrVideoExists) {
+		t.Fatalf("PutVideo() error = %v, want %v", err, ErrVideoExists)
+	}
+}
@@ -3,16 +3,129 @@ package storage
 import (
 	"context"
 	"io"
+	"strings"
+	"time"
 )
 
 type VideoMeta struct {
 	Filename string
+	Title    string
+	Category string
+	Tags     []string
+	Duration time.Duration
+}
+
+type FileInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+	// ETag версия файла в хранилище, если хранилище ее сообщает
+	ETag     string
+	Meta     VideoMeta
+	Excluded bool
+	Reason   string
+}
+
+// Matches проверяет, содержат ли имя файла, заголовок, категория или теги строку поиска без учета регистра
+func (f FileInfo) Matches(query string) bool {
+	query = strings.ToLower(query)
+	fields := append([]string{f.Key, f.Meta.Title, f.Meta.Category}, f.Meta.Tags...)
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getNextVideo выбирает и открывает следующее видео, общая реализация GetNextVideo для хранилищ
+func getNextVideo(ctx context.Context, s Storage) (io.ReadCloser, int64, *VideoMeta, error) {
+	file, err := s.PickNextVideo()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	video, size, err := s.OpenVideo(ctx, file.Key)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	meta := file.Meta
+	return video, size, &meta, nil
+}
+
+// MatchScore оценивает, насколько видео подходит под поисковый запрос без учета регистра: каждое слово запроса должно
+// найтись в имени файла, названии, категории или тегах целиком или хотя бы как буквы в том же порядке ("прхжд" найдет
+// "прохождение"). Совпадение с началом поля ценится выше. Возвращает 0, если видео не подходит
+func (f FileInfo) MatchScore(query string) int {
+	fields := append([]string{f.Key, f.Meta.Title, f.Meta.Category}, f.Meta.Tags...)
+	for i, field := range fields {
+		fields[i] = strings.ToLower(field)
+	}
+
+	score := 0
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		best := 0
+		for _, field := range fields {
+			switch {
+			case strings.HasPrefix(field, word):
+				best = max(best, 3)
+			case strings.Contains(field, word):
+				best = max(best, 2)
+			case isSubsequence(word, field):
+				best = max(best, 1)
+			}
+		}
+
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+
+	return score
+}
+
+// isSubsequence проверяет, что все буквы sub встречаются в s в том же порядке
+func isSubsequence(sub string, s string) bool {
+	rest := []rune(sub)
+	for _, r := range s {
+		if len(rest) == 0 {
+			break
+		}
+		if r == rest[0] {
+			rest = rest[1:]
+		}
+	}
+
+	return len(rest) == 0
 }
 
 type Storage interface {
-	GetNextVideo() (io.ReadCloser, int64, *VideoMeta)
+	// GetNextVideo выбирает и открывает следующее видео, возвращает ErrNoVideo, если в библиотеке нет подходящих видео.
+	// ctx контекст воспроизведения видео, после его отмены чтение видео прекращается
+	GetNextVideo(ctx context.Context) (io.ReadCloser, int64, *VideoMeta, error)
+	// PickNextVideo выбирает следующее видео, не открывая его
+	PickNextVideo() (FileInfo, error)
+	// PeekNextVideo возвращает видео, которое будет выбрано следующим, не убирая его из очереди
+	PeekNextVideo() (FileInfo, bool)
+	// OpenVideo открывает видео из библиотеки и возвращает его размер
+	OpenVideo(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// PutVideo сохраняет видео в хранилище под ключом библиотеки, возвращает ErrReadOnly, если хранилище это не поддерживает,
+	// и ErrVideoExists, если ключ уже занят. Видео появится в библиотеке после UpdateFilesList
+	PutVideo(ctx context.Context, key string, video io.ReadSeeker) error
+	// DeleteVideo удаляет видео вместе с файлом метаданных
+	DeleteVideo(ctx context.Context, key string) error
+	// RenameVideo переименовывает видео вместе с файлом метаданных, возвращает ErrVideoExists, если newKey уже занят
+	RenameVideo(ctx context.Context, key string, newKey string) error
+	// SetVideoMeta записывает название, категорию, теги и длительность видео в файл метаданных рядом с ним
+	SetVideoMeta(ctx context.Context, key string, meta VideoMeta) error
 	UpdateFilesList(context.Context) error
 	GetQueue() []string
 	AddToQueue(key string)
+	SetQueue(keys []string) error
 	GetFilesList() []string
+	GetFilesInfo() []FileInfo
+	Ping(context.Context) error
 }
@@ -0,0 +1,25 @@
+package storage
+
+import "testing"
+
+func TestMatchesIsSubstring(t *testing.T) {
+	file := FileInfo{Key: "vods/a_b_c.ts", Meta: VideoMeta{Title: "Прохождение", Tags: []string{"Speedrun"}}}
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "a_b", want: true},
+		{query: "ПРОХОЖ", want: true},
+		{query: "speedrun", want: true},
+		// Буквы не подряд не считаются совпадением
+		{query: "abc", want: false},
+		{query: "прхжд", want: false},
+	}
+
+	for _, test := range tests {
+		if got := file.Matches(test.query); got != test.want {
+			t.Errorf("Matches(%q) = %v, want %v", test.query, got, test.want)
+		}
+	}
+}
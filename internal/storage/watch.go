@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultWatchQuietPeriod = 10 * time.Second
+	// watchCheckInterval как часто проверять, что измененные файлы перестали изменяться
+	watchCheckInterval = time.Second
+	// watchPollInterval интервал опроса папки, если fsnotify недоступен
+	watchPollInterval = 30 * time.Second
+)
+
+// Changes изменения списка видео, доступных для трансляции
+type Changes struct {
+	Added   []string
+	Removed []string
+}
+
+func (c Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
+}
+
+// watchable хранилище, которое само следит за изменениями: watch блокируется до отмены контекста,
+// обновляет список видео и после каждого обновления вызывает changed
+type watchable interface {
+	// watching включено ли отслеживание изменений
+	watching() bool
+	watch(ctx context.Context, changed func())
+}
+
+// Wrapper хранилище-обертка, например кэш или метрики
+type Wrapper interface {
+	Unwrap() Storage
+}
+
+// Watch начинает следить за изменениями в хранилище, пока не отменен контекст, и сообщает в onChange, какие видео
+// появились и пропали. Возвращает false, если для хранилища не включено отслеживание изменений
+func Watch(ctx context.Context, s Storage, onChange func(Changes)) bool {
+	inner := s
+	for {
+		if _, ok := inner.(watchable); ok {
+			break
+		}
+
+		wrapper, ok := inner.(Wrapper)
+		if !ok {
+			return false
+		}
+		inner = wrapper.Unwrap()
+	}
+
+	watcher := inner.(watchable)
+	if !watcher.watching() {
+		return false
+	}
+
+	// Изменения нескольких источников приходят из разных горутин
+	var mu sync.Mutex
+	before := s.GetFilesList()
+
+	go watcher.watch(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		after := s.GetFilesList()
+		changes := diffKeys(before, after)
+		before = after
+
+		if !changes.IsEmpty() {
+			onChange(changes)
+		}
+	})
+
+	return true
+}
+
+func diffKeys(before, after []string) Changes {
+	changes := Changes{}
+	for _, key := range after {
+		if !slices.Contains(before, key) {
+			changes.Added = append(changes.Added, key)
+		}
+	}
+
+	for _, key := range before {
+		if !slices.Contains(after, key) {
+			changes.Removed = append(changes.Removed, key)
+		}
+	}
+
+	return changes
+}
+
+// relist перечитывает список видео хранилища с интервалом, пока не отменен контекст
+func relist(ctx context.Context, s Storage, interval time.Duration, changed func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reload(ctx, s, changed)
+		}
+	}
+}
+
+func reload(ctx context.Context, s Storage, changed func()) {
+	if err := s.UpdateFilesList(ctx); err != nil {
+		zerolog.Ctx(ctx).Warn().Err(err).Msg("Unable to update files list")
+		return
+	}
+
+	changed()
+}
@@ -0,0 +1,235 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+const testQuietPeriod = 100 * time.Millisecond
+
+// wrappedStorage обертка, как кэш или метрики, через которую Watch находит хранилище
+type wrappedStorage struct {
+	Storage
+}
+
+func (s wrappedStorage) Unwrap() Storage {
+	return s.Storage
+}
+
+func newWatchedDisk(t *testing.T, params DiskStorageParams) *diskStorage {
+	t.Helper()
+
+	params.PickStrategy = config.PickStrategySequential
+	params.Watch = true
+	params.QuietPeriod = testQuietPeriod
+
+	st, err := NewDiskStorage(context.Background(), params)
+	if err != nil {
+		t.Fatalf("NewDiskStorage: %v", err)
+	}
+
+	disk := st.(*diskStorage)
+	disk.checkInterval = 10 * time.Millisecond
+	disk.pollInterval = 20 * time.Millisecond
+
+	return disk
+}
+
+// writeVideo записывает файл, old делает его записанным давно, чтобы он не ждал quietPeriod
+func writeVideo(t *testing.T, path string, old bool) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(filepath.Base(path)), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	if old {
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatalf("Chtimes: %v", err)
+		}
+	}
+}
+
+// startWatch начинает следить за хранилищем и возвращает канал с изменениями
+func startWatch(t *testing.T, s Storage) <-chan Changes {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	changes := make(chan Changes, 10)
+	if !Watch(ctx, wrappedStorage{s}, func(c Changes) { changes <- c }) {
+		t.Fatal("Watch() = false, want true")
+	}
+
+	return changes
+}
+
+func waitChanges(t *testing.T, changes <-chan Changes, want Changes) {
+	t.Helper()
+
+	select {
+	case got := <-changes:
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("changes = %+v, want %+v", got, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no changes, want %+v", want)
+	}
+}
+
+func TestDiffKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		after  []string
+		want   Changes
+	}{
+		{name: "same", before: []string{"a.ts", "b.ts"}, after: []string{"b.ts", "a.ts"}, want: Changes{}},
+		{name: "added", before: []string{"a.ts"}, after: []string{"a.ts", "b.ts", "c.ts"}, want: Changes{Added: []string{"b.ts", "c.ts"}}},
+		{name: "removed", before: []string{"a.ts", "b.ts"}, after: []string{"b.ts"}, want: Changes{Removed: []string{"a.ts"}}},
+		// Переименование выглядит как удаление и добавление
+		{name: "renamed", before: []string{"a.ts"}, after: []string{"b.ts"}, want: Changes{Added: []string{"b.ts"}, Removed: []string{"a.ts"}}},
+		{name: "from empty", before: nil, after: []string{"a.ts"}, want: Changes{Added: []string{"a.ts"}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diffKeys(test.before, test.after)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("diffKeys() = %+v, want %+v", got, test.want)
+			}
+			if got.IsEmpty() != (len(test.want.Added) == 0 && len(test.want.Removed) == 0) {
+				t.Fatalf("IsEmpty() = %v for %+v", got.IsEmpty(), got)
+			}
+		})
+	}
+}
+
+func TestWatchRequiresWatching(t *testing.T) {
+	st, err := NewDiskStorage(context.Background(), DiskStorageParams{
+		PickStrategy:  config.PickStrategySequential,
+		DirectoryPath: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewDiskStorage: %v", err)
+	}
+
+	if Watch(context.Background(), wrappedStorage{st}, func(Changes) {}) {
+		t.Fatal("Watch() = true for storage without watch, want false")
+	}
+
+	// Хранилище, которое не умеет следить за изменениями и ничего не оборачивает
+	if Watch(context.Background(), newStubStorage(), func(Changes) {}) {
+		t.Fatal("Watch() = true for storage without watching support, want false")
+	}
+}
+
+func TestDiskQuietPeriodGate(t *testing.T) {
+	dir := t.TempDir()
+	st := newWatchedDisk(t, DiskStorageParams{DirectoryPath: dir})
+	st.quietPeriod = time.Hour
+
+	writeVideo(t, filepath.Join(dir, "recording.ts"), false)
+	if err := st.UpdateFilesList(context.Background()); err != nil {
+		t.Fatalf("UpdateFilesList: %v", err)
+	}
+	if files := st.GetFilesList(); len(files) > 0 {
+		t.Fatalf("GetFilesList() = %v while file is being written, want empty", files)
+	}
+	if info := st.GetFilesInfo(); len(info) != 1 || info[0].Reason != "file is being written" {
+		t.Fatalf("GetFilesInfo() = %+v, want file being written", info)
+	}
+
+	// Загруженное через PutVideo видео записано целиком и не ждет quietPeriod
+	if err := st.PutVideo(context.Background(), "uploaded.ts", bytes.NewReader([]byte("video"))); err != nil {
+		t.Fatalf("PutVideo: %v", err)
+	}
+	if err := st.UpdateFilesList(context.Background()); err != nil {
+		t.Fatalf("UpdateFilesList: %v", err)
+	}
+	if files := st.GetFilesList(); !reflect.DeepEqual(files, []string{"uploaded.ts"}) {
+		t.Fatalf("GetFilesList() = %v, want [uploaded.ts]", files)
+	}
+
+	// Если загруженный файл потом изменили, он снова ждет quietPeriod
+	changed := time.Now().Add(time.Second)
+	if err := os.Chtimes(filepath.Join(dir, "uploaded.ts"), changed, changed); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	// Давно записанный файл доступен сразу
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "recording.ts"), past, past); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+	if err := st.UpdateFilesList(context.Background()); err != nil {
+		t.Fatalf("UpdateFilesList: %v", err)
+	}
+	if files := st.GetFilesList(); !reflect.DeepEqual(files, []string{"recording.ts"}) {
+		t.Fatalf("GetFilesList() = %v, want [recording.ts]", files)
+	}
+}
+
+func TestDiskWatchNotifiesChanges(t *testing.T) {
+	dir := t.TempDir()
+	writeVideo(t, filepath.Join(dir, "old.ts"), true)
+
+	st := newWatchedDisk(t, DiskStorageParams{DirectoryPath: dir})
+	changes := startWatch(t, st)
+
+	written := time.Now()
+	writeVideo(t, filepath.Join(dir, "new.ts"), false)
+	waitChanges(t, changes, Changes{Added: []string{"new.ts"}})
+	if elapsed := time.Since(written); elapsed < testQuietPeriod {
+		t.Fatalf("new file added after %s, want after quiet period %s", elapsed, testQuietPeriod)
+	}
+
+	// Вложенная папка, созданная после запуска, тоже отслеживается
+	if err := os.Mkdir(filepath.Join(dir, "clips"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	// Даем watcher заметить папку, иначе событие о файле в ней может прийти раньше, чем за ней начнут следить
+	time.Sleep(50 * time.Millisecond)
+	writeVideo(t, filepath.Join(dir, "clips", "clip.ts"), false)
+	waitChanges(t, changes, Changes{Added: []string{"clips/clip.ts"}})
+
+	if err := os.Remove(filepath.Join(dir, "old.ts")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	waitChanges(t, changes, Changes{Removed: []string{"old.ts"}})
+}
+
+func TestDiskWatchPollsWithoutDirectory(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.ts")
+	later := filepath.Join(dir, "later.ts")
+	writeVideo(t, existing, true)
+
+	// Без папки fsnotify не к чему подключить, и список файлов опрашивается с интервалом
+	st := newWatchedDisk(t, DiskStorageParams{Files: []string{existing, later}})
+	if files := st.GetFilesList(); !reflect.DeepEqual(files, []string{existing}) {
+		t.Fatalf("GetFilesList() = %v, want [%s]", files, existing)
+	}
+
+	changes := startWatch(t, st)
+
+	written := time.Now()
+	writeVideo(t, later, false)
+	waitChanges(t, changes, Changes{Added: []string{later}})
+	if elapsed := time.Since(written); elapsed < testQuietPeriod {
+		t.Fatalf("file added after %s, want after quiet period %s", elapsed, testQuietPeriod)
+	}
+
+	if err := os.Remove(existing); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	waitChanges(t, changes, Changes{Removed: []string{existing}})
+}
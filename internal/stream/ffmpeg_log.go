@@ -0,0 +1,32 @@
+package stream
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// ffmpegLevels префиксы уровней, которые ffmpeg добавляет к строкам с флагом -loglevel level+...
+var ffmpegLevels = map[string]zerolog.Level{
+	"[panic] ":   zerolog.ErrorLevel,
+	"[fatal] ":   zerolog.ErrorLevel,
+	"[error] ":   zerolog.ErrorLevel,
+	"[warning] ": zerolog.WarnLevel,
+	"[info] ":    zerolog.InfoLevel,
+	"[verbose] ": zerolog.DebugLevel,
+	"[debug] ":   zerolog.DebugLevel,
+	"[trace] ":   zerolog.TraceLevel,
+}
+
+// parseFfmpegLevel определяет уровень строки вывода ffmpeg и убирает из нее префикс уровня.
+// Префикс может стоять после имени компонента, например "[flv @ 0x5581] [error] ...".
+// Строки без префикса считаются информационными
+func parseFfmpegLevel(line string) (zerolog.Level, string) {
+	for prefix, level := range ffmpegLevels {
+		if i := strings.Index(line, prefix); i >= 0 {
+			return level, line[:i] + line[i+len(prefix):]
+		}
+	}
+
+	return zerolog.InfoLevel, line
+}
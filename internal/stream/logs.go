@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+const (
+	DefaultLogsHistory = 200
+	logsSubscriberBuf  = 64
+)
+
+type LogLine struct {
+	Time     time.Time       `json:"time"`
+	Platform config.Platform `json:"platform"`
+	Level    string          `json:"level"`
+	Text     string          `json:"text"`
+}
+
+// Logs рассылает строки вывода ffmpeg подписчикам и хранит последние строки для новых подписчиков
+type Logs struct {
+	mu sync.Mutex
+
+	historySize int
+	history     []LogLine
+	subscribers map[chan LogLine]struct{}
+}
+
+func NewLogs(historySize int) *Logs {
+	return &Logs{
+		historySize: historySize,
+		history:     make([]LogLine, 0, historySize),
+		subscribers: map[chan LogLine]struct{}{},
+	}
+}
+
+// Publish отправляет строку подписчикам, медленные подписчики пропускают строки, чтобы не тормозить ffmpeg
+func (l *Logs) Publish(line LogLine) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.history) >= l.historySize {
+		l.history = slices.Delete(l.history, 0, len(l.history)-l.historySize+1)
+	}
+	l.history = append(l.history, line)
+
+	for ch := range l.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+func (l *Logs) History() []LogLine {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return slices.Clone(l.history)
+}
+
+// Subscribe возвращает канал новых строк и функцию отписки
+func (l *Logs) Subscribe() (<-chan LogLine, func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ch := make(chan LogLine, logsSubscriberBuf)
+	l.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		delete(l.subscribers, ch)
+	}
+}
@@ -0,0 +1,44 @@
+package stream
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+)
+
+// progressKeys ключи, которые ffmpeg пишет с флагом -progress
+var progressKeys = map[string]struct{}{
+	"frame": {}, "fps": {}, "bitrate": {}, "total_size": {}, "out_time_us": {}, "out_time_ms": {},
+	"out_time": {}, "dup_frames": {}, "drop_frames": {}, "speed": {}, "progress": {},
+}
+
+// observeProgress обновляет метрики по строке статистики ffmpeg, возвращает false, если строка не является статистикой
+func observeProgress(platform config.Platform, line string) bool {
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return false
+	}
+
+	if _, known := progressKeys[key]; !known && !strings.HasPrefix(key, "stream_") {
+		return false
+	}
+
+	switch key {
+	case "fps":
+		if fps, err := strconv.ParseFloat(value, 64); err == nil {
+			monitoring.FfmpegFPS.WithLabelValues(string(platform)).Set(fps)
+		}
+	case "bitrate":
+		if bitrate, err := strconv.ParseFloat(strings.TrimSuffix(value, "kbits/s"), 64); err == nil {
+			monitoring.FfmpegBitrate.WithLabelValues(string(platform)).Set(bitrate)
+		}
+	case "speed":
+		if speed, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "x"), 64); err == nil {
+			monitoring.FfmpegSpeed.WithLabelValues(string(platform)).Set(speed)
+		}
+	}
+
+	return true
+}
@@ -3,8 +3,12 @@ package stream
 import (
 	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Perkovec/StatiStream/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type Stream interface {
@@ -15,6 +19,7 @@ type Stream interface {
 	HasToken() bool
 	IsStarted() bool
 	NextVideo() <-chan struct{}
+	LastWrite() time.Time
 }
 
 type Streams map[config.Platform]Stream
@@ -48,3 +53,25 @@ func (s Streams) SetVideo(video io.ReadCloser, contentLength int64) {
 		stream.SetVideo(video, contentLength)
 	}
 }
+
+// lockedWriter не дает нескольким видео одновременно писать в stdin ffmpeg при переключении,
+// считает переданные байты и запоминает время последней записи
+type lockedWriter struct {
+	mu        *sync.Mutex
+	w         io.Writer
+	counter   prometheus.Counter
+	lastWrite *atomic.Int64
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, err := w.w.Write(p)
+	w.counter.Add(float64(n))
+	if n > 0 {
+		w.lastWrite.Store(time.Now().UnixNano())
+	}
+
+	return n, err
+}
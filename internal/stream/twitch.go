@@ -7,9 +7,14 @@ import (
 	"io"
 	"os/exec"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Perkovec/StatiStream/internal/config"
 	"github.com/Perkovec/StatiStream/internal/helpers"
+	"github.com/Perkovec/StatiStream/internal/monitoring"
+	"github.com/rs/zerolog"
 )
 
 const (
@@ -17,25 +22,40 @@ const (
 )
 
 type twitchStream struct {
+	mu sync.Mutex
+
 	ffmpegPath string
 	token      string
+	logs       *Logs
+	logger     zerolog.Logger
 
 	streamProcess      *exec.Cmd
 	streamProcessStdin io.WriteCloser
-	stopCh             chan struct{}
-	nextCh             chan struct{}
-
-	ctx    context.Context
-	cancel context.CancelFunc
+	// processDone закрывается, когда процесс ffmpeg завершился
+	processDone chan struct{}
+	stdinMu     sync.Mutex
+	stopCh      chan struct{}
+	nextCh      chan struct{}
+
+	ctx         context.Context
+	cancel      context.CancelFunc
+	videoCancel context.CancelFunc
+
+	startsCount int
+	lastWrite   atomic.Int64
 }
 
 type TwitchStreamParams struct {
 	FfmpegPath string
+	Logs       *Logs
+	Logger     zerolog.Logger
 }
 
 func NewTwitchStream(params TwitchStreamParams) Stream {
 	return &twitchStream{
 		ffmpegPath: params.FfmpegPath,
+		logs:       params.Logs,
+		logger:     params.Logger.With().Str("platform", string(config.PlatformTwitch)).Logger(),
 		stopCh:     make(chan struct{}),
 		nextCh:     make(chan struct{}),
 	}
@@ -46,53 +66,111 @@ func (s *twitchStream) GetPlatform() config.Platform {
 }
 
 func (s *twitchStream) HasToken() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.token != ""
 }
 
 func (s *twitchStream) SetStreamToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.token = token
 }
 
 func (s *twitchStream) IsStarted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return s.streamProcess != nil
 }
 
 func (s *twitchStream) Stop() error {
-	if !s.IsStarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streamProcess == nil {
 		return nil
 	}
 
+	if s.videoCancel != nil {
+		s.videoCancel()
+	}
+
 	if s.cancel != nil {
 		s.cancel()
 	}
 
-	return s.streamProcess.Process.Kill()
+	process := s.streamProcess
+	s.streamProcess = nil
+	s.streamProcessStdin = nil
+	monitoring.StreamUp.WithLabelValues(string(s.GetPlatform())).Set(0)
+
+	err := process.Process.Kill()
+	if err != nil {
+		return fmt.Errorf("TwitchStream.Stop.Kill: %w", err)
+	}
+
+	// Дожидаемся завершения процесса в watchProcess, чтобы не оставлять зомби
+	<-s.processDone
+
+	return nil
 }
 
+// SetVideo начинает передавать видео в ffmpeg, передача предыдущего видео при этом прекращается
 func (s *twitchStream) SetVideo(video io.ReadCloser, contentLength int64) {
-	if !s.IsStarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streamProcess == nil {
+		video.Close()
 		return
 	}
 
-	videoCtx := helpers.NewReader(s.ctx, video, contentLength, s.nextCh)
+	if s.videoCancel != nil {
+		s.videoCancel()
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.videoCancel = cancel
+
+	videoCtx := helpers.NewReader(ctx, video, contentLength, s.nextCh)
+	stdin := &lockedWriter{
+		mu:        &s.stdinMu,
+		w:         s.streamProcessStdin,
+		counter:   monitoring.FfmpegInputBytes.WithLabelValues(string(s.GetPlatform())),
+		lastWrite: &s.lastWrite,
+	}
 
-	nullPacket := make([]byte, 188)
-	nullPacket[0] = 0x47
-	nullPacket[1] = 0x1F
-	nullPacket[2] = 0xFF
-	nullPacket[3] = 0x10
-	s.streamProcessStdin.Write(nullPacket)
+	// Запись в stdin может блокироваться, пока ffmpeg не прочитает данные, поэтому пишем вне блокировки
+	go func() {
+		defer video.Close()
+
+		nullPacket := make([]byte, 188)
+		nullPacket[0] = 0x47
+		nullPacket[1] = 0x1F
+		nullPacket[2] = 0xFF
+		nullPacket[3] = 0x10
+		if _, err := stdin.Write(nullPacket); err != nil {
+			return
+		}
 
-	go io.Copy(s.streamProcessStdin, videoCtx)
+		io.Copy(stdin, videoCtx)
+	}()
 }
 
 func (s *twitchStream) Start() error {
-	if s.IsStarted() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streamProcess != nil {
 		return nil
 	}
 
 	var command = []string{
-		"-loglevel", "warning",
+		"-loglevel", "level+warning", // префикс [warning]/[error] нужен, чтобы определить уровень строки
+		"-progress", "pipe:2", // статистика для метрик в stderr в формате key=value
 		"-re",
 		"-f", "mpegts",
 		"-i", "pipe:0",
@@ -125,7 +203,7 @@ func (s *twitchStream) Start() error {
 		return fmt.Errorf("TwitchStream.Start.Start: %w", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(s.logger.WithContext(context.Background()))
 	s.ctx = ctx
 	s.cancel = cancel
 
@@ -133,10 +211,60 @@ func (s *twitchStream) Start() error {
 
 	s.streamProcess = r
 	s.streamProcessStdin = stdin
+	s.processDone = make(chan struct{})
+
+	go s.watchProcess(r, s.processDone)
+
+	platform := string(s.GetPlatform())
+	if s.startsCount > 0 {
+		monitoring.FfmpegRestarts.WithLabelValues(platform).Inc()
+	}
+	s.startsCount++
+	monitoring.StreamUp.WithLabelValues(platform).Set(1)
 
 	return nil
 }
 
+// watchProcess ждет завершения ffmpeg, если процесс завершился не через Stop, то трансляция считается остановленной
+func (s *twitchStream) watchProcess(process *exec.Cmd, done chan struct{}) {
+	err := process.Wait()
+	close(done)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streamProcess != process {
+		return
+	}
+
+	if s.videoCancel != nil {
+		s.videoCancel()
+	}
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+
+	s.streamProcess = nil
+	s.streamProcessStdin = nil
+
+	platform := string(s.GetPlatform())
+	monitoring.StreamUp.WithLabelValues(platform).Set(0)
+	monitoring.FfmpegUnexpectedExits.WithLabelValues(platform).Inc()
+
+	s.logger.Error().Err(err).Msg("ffmpeg exited unexpectedly")
+}
+
+// LastWrite возвращает время последней записи видео в ffmpeg
+func (s *twitchStream) LastWrite() time.Time {
+	lastWrite := s.lastWrite.Load()
+	if lastWrite == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, lastWrite)
+}
+
 func (s *twitchStream) NextVideo() <-chan struct{} {
 	c := make(chan struct{})
 
@@ -160,12 +288,22 @@ func (s *twitchStream) captureOutput(ctx context.Context, r io.Reader) {
 		default:
 			line, err = reader.ReadString('\n')
 			if err != nil && err != io.EOF {
-				fmt.Println("unable to read ffmpeg output")
+				s.logger.Error().Err(err).Msg("Unable to read ffmpeg output")
 				return
 			}
 			line = strings.TrimSpace(line)
+			if observeProgress(s.GetPlatform(), line) {
+				continue
+			}
 			if line != "" {
-				fmt.Printf("ffmpeg output: %s\n", line)
+				level, text := parseFfmpegLevel(line)
+				s.logger.WithLevel(level).Str("source", "ffmpeg").Msg(text)
+				s.logs.Publish(LogLine{
+					Time:     time.Now(),
+					Platform: s.GetPlatform(),
+					Level:    level.String(),
+					Text:     text,
+				})
 			}
 			if err != nil {
 				return
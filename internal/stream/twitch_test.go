@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// failingVideo отдает часть видео, а потом ошибку, как читатель s3 или HTTP, исчерпавший попытки переоткрыть видео
+type failingVideo struct {
+	data   []byte
+	err    error
+	closed chan struct{}
+}
+
+func (v *failingVideo) Read(p []byte) (int, error) {
+	if len(v.data) == 0 {
+		return 0, v.err
+	}
+
+	n := copy(p, v.data)
+	v.data = v.data[n:]
+
+	return n, nil
+}
+
+func (v *failingVideo) Close() error {
+	close(v.closed)
+
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// newRunningTwitchStream возвращает трансляцию, которая считает ffmpeg запущенным, вход ffmpeg отбрасывается
+func newRunningTwitchStream(t *testing.T) *twitchStream {
+	t.Helper()
+
+	s := NewTwitchStream(TwitchStreamParams{Logger: zerolog.Nop()}).(*twitchStream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	s.ctx = ctx
+	s.cancel = cancel
+	s.streamProcess = &exec.Cmd{}
+	s.streamProcessStdin = nopWriteCloser{io.Discard}
+
+	return s
+}
+
+func TestSetVideoSwitchesToNextAfterReadError(t *testing.T) {
+	s := newRunningTwitchStream(t)
+	video := &failingVideo{
+		data:   make([]byte, 188*10),
+		err:    errors.New("resumingReader: retry budget spent at offset 1880"),
+		closed: make(chan struct{}),
+	}
+
+	s.SetVideo(video, 188*100)
+
+	select {
+	case <-s.NextVideo():
+	case <-time.After(5 * time.Second):
+		t.Fatal("next video was not requested after the read error")
+	}
+
+	select {
+	case <-video.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("failed video was not closed")
+	}
+}
+
+// blockedVideo ждет сигнала и только после него возвращает ошибку
+type blockedVideo struct {
+	release chan error
+}
+
+func (v *blockedVideo) Read(p []byte) (int, error) {
+	return 0, <-v.release
+}
+
+func (v *blockedVideo) Close() error {
+	return nil
+}
+
+func TestSetVideoReplacedDoesNotSwitchToNext(t *testing.T) {
+	s := newRunningTwitchStream(t)
+	first := &blockedVideo{release: make(chan error, 1)}
+	second := &blockedVideo{release: make(chan error, 1)}
+	t.Cleanup(func() { second.release <- io.ErrClosedPipe })
+
+	s.SetVideo(first, 188*100)
+	s.SetVideo(second, 188*100)
+
+	// Ошибка чтения замененного видео не должна переключать уже новое видео
+	first.release <- io.ErrClosedPipe
+
+	select {
+	case <-s.NextVideo():
+		t.Fatal("next video was requested after the replaced video failed")
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,61 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Perkovec/StatiStream/internal/config"
+)
+
+// Extension расширение видео после перекодирования
+const Extension = ".ts"
+
+// presets параметры ffmpeg для подготовки видео к трансляции на Twitch, те же, что в README
+var presets = map[config.TranscodePreset][]string{
+	config.TranscodePresetCPU: {
+		"-c:v", "libx264", "-preset", "slow", "-profile:v", "main",
+	},
+	config.TranscodePresetNVENC: {
+		"-c:v", "h264_nvenc", "-preset", "slow", "-profile:v", "main",
+	},
+}
+
+// commonArgs общие для всех пресетов параметры битрейта, звука и контейнера
+var commonArgs = []string{
+	"-b:v", "5000k", "-maxrate", "5000k", "-bufsize", "6000k",
+	"-c:a", "aac", "-b:a", "128k", "-ar", "44100", "-ac", "2",
+	"-vf", "format=yuv420p", "-r", "30",
+	"-flags", "+global_header", "-fflags", "+genpts",
+	"-f", "mpegts",
+}
+
+// Run перекодирует видео input в MPEG-TS output по пресету
+func Run(ctx context.Context, ffmpegPath string, preset config.TranscodePreset, input string, output string) error {
+	presetArgs, ok := presets[preset]
+	if !ok {
+		return fmt.Errorf("transcode.Run: unknown preset '%s'", preset)
+	}
+
+	args := []string{"-hide_banner", "-loglevel", "error", "-y"}
+	if preset == config.TranscodePresetNVENC {
+		args = append(args, "-hwaccel", "cuda")
+	}
+	args = append(args, "-i", input)
+	args = append(args, presetArgs...)
+	args = append(args, commonArgs...)
+	args = append(args, output)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath, args...)
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("transcode.Run: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}